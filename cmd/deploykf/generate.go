@@ -1,18 +1,23 @@
 package deploykf
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"io"
-	"os"
-	"path/filepath"
-	"strconv"
 	"strings"
+	"sync"
+	"time"
 
-	"github.com/hairyhenderson/gomplate/v3"
 	"github.com/spf13/cobra"
 
+	"github.com/deployKF/cli/internal/exitcode"
 	"github.com/deployKF/cli/internal/generate"
-	"github.com/deployKF/cli/internal/version"
+	"github.com/deployKF/cli/internal/logging"
+	"github.com/deployKF/cli/internal/project"
+	"github.com/deployKF/cli/internal/values"
+	"github.com/deployKF/cli/pkg/generator"
 )
 
 const generateHelp = `This command will generate an output folder containing Kubernetes manifests.
@@ -26,12 +31,136 @@ You must provide either '--source-version' OR '--source-path' to specify the sou
 
 You may provide one or more '--values' files that contain your configuration values:
  - For more information on how to structure your values files, see the 'deployKF/deployKF' GitHub repository.
-
-You must provide '--output-dir' to specify the output directory for the generated manifests:
+ - Later '--values' take precedence over earlier ones.
+ - Before the source is downloaded, every '--values' must exist and contain a YAML mapping without duplicate keys
+   ('https://' URLs are checked when they are downloaded).
+ - Values encrypted with SOPS are detected, and decrypted in memory by the 'sops' command with your local age or PGP keys
+   (the plaintext is never written to disk, set 'DEPLOYKF_SOPS_COMMAND' to use a different 'sops' binary).
+   On Windows, encrypted values must be read from a local file (not stdin, an environment variable or a URL).
+ - Use '--values -' to read values from stdin (at most once).
+ - Use '--values env:NAME' to read values from the environment variable 'NAME' (handy for CI secrets).
+ - Use '--values https://...' to download values, or '--values file://...' for a local file (no other URL schemes are allowed).
+ - Keys which don't exist in the 'default_values.yaml' of the source (or its 'values_schema.json') have no effect,
+   so they are warned about with "did you mean" suggestions, use '--strict' to fail instead.
+ - Free-form mappings (like labels) may contain any keys, if their 'values_schema.json' property sets
+   '"x-deploykf-open": true' or 'additionalProperties'.
+
+You must provide one of '--output-dir', '--output-archive' OR '--output -' to specify where the generated manifests are written.
+
+If '--output-dir' is provided, the manifests are written into that directory:
  - If the directory does not exist, it will be created.
- - If the directory is non-empty, it will be cleaned before generating the manifests.
+ - If the directory is non-empty, it will be cleaned before writing the generated manifests.
    However, it must contain a '.deploykf_output' marker file, otherwise the command will fail.
 
+If '--output-archive' is provided, the manifests are packaged into a single '.tar.gz', '.tgz' or '.zip' file:
+ - The archive contains the generated manifests and the '.deploykf_output' marker file.
+ - The archive is reproducible: identical inputs produce byte-identical archives.
+   For this reason, the marker in an archive does NOT contain 'generated_at'.
+ - Templates see the archive path (without its extension) as the output directory.
+
+If '--output -' is provided, the manifests are written to stdout as a single multi-document YAML stream:
+ - Each document is prefixed with a '# Source: <relative path>' comment.
+ - Only '.yaml' and '.yml' files are included (like all commands, log messages are written to stderr).
+ - Templates see '.' as the output directory.
+
+After rendering, every generated '.yaml' and '.yml' file is checked for valid YAML syntax:
+ - Files with syntax errors are reported with their line number and originating template.
+ - The command fails if any file is invalid, unless '--allow-invalid-yaml' is provided.
+
+If '--validate' is provided, every generated Kubernetes object is validated against its JSON schema:
+ - Schemas are read from each '--schema-location' directory, and from the 'schemas' folder of the generator source.
+ - Directories use the same layout as kubeconform, for example, 'kubernetes-json-schema' for built-in types
+   (selected with '--kube-version'), and '{group}/{kind}_{version}.json' for custom resources.
+ - Objects without a schema are skipped with a warning, and the command fails if no schemas are found at all.
+ - The following schema keywords are not checked: 'format', 'patternProperties', 'dependencies',
+   'if'/'then'/'else', 'contains', '$ref' to other files, and 'x-kubernetes-validations' (CEL rules).
+ - Use '--validate-output json' to print the results as JSON (for example, to annotate CI runs).
+
+If '--scan-secrets' is provided, the generated manifests are scanned for possible secret leaks:
+ - 'Secret' objects with non-empty 'data' or 'stringData' literals.
+ - 'ConfigMap' entries with credential-like keys (e.g. 'password') or values (e.g. private keys, access tokens).
+ - Any value which exactly matches a values key marked with '"x-deploykf-sensitive": true'
+   in the 'values_schema.json' of the generator source.
+ - The command fails if anything is found, unless '--allow-secrets' is provided to acknowledge the findings.
+
+If '--watch' is provided, the command keeps running, and re-generates the manifests into the '--output-dir'
+every time one of the '--values' files or the '--source-path' changes:
+ - Changes are detected by checking the files every '--watch-interval', and are debounced until the files stop changing.
+ - After each run, a summary of the added (+), modified (~) and removed (-) output files is printed.
+ - Errors are printed, but don't stop the command, so you can fix the problem and save again.
+ - Press Ctrl+C to stop watching.
+ - If '--timeout' is provided, it limits each run (not the total time spent watching).
+
+If '--timeout' is provided, the command fails with exit code 11 if generating takes longer (like when it is interrupted):
+ - Downloading, unpacking, rendering and checking are stopped at the next safe point, and temporary files are removed.
+ - The '--output-dir' is only cleaned and written after all manifests are rendered and checked,
+   so a failed, interrupted or timed-out run leaves the previous manifests in place.
+ - With '--all-envs', the timeout applies to generating all environments.
+
+You may provide one or more '--set' overrides, like '--set deploykf_core.deploykf_auth.dex.expiry.idTokens=24h':
+ - The value is parsed as YAML, so 'true' is a boolean, and "'true'" is a string.
+ - Overrides take precedence over all '--values' files.
+
+If '--env' or '--all-envs' is provided, the settings are read from the project config file (see '--config'):
+ - The file declares named 'environments', each with its own source, values, set overrides and output directory.
+ - The optional 'defaults' apply to every environment (their 'values' are merged first).
+ - Relative paths are resolved against the directory of the project config file.
+ - Flags that enable checks (like '--validate') are applied on top of the settings of each environment.
+ - With '--all-envs', each distinct generator source is only downloaded (or copied) and verified once,
+   then up to '--parallel' environments are generated concurrently.
+   Only one environment renders its templates at a time (gomplate is not safe for concurrent use),
+   the other steps (like reading values, checks and writing the output) run concurrently.
+ - With '--all-envs' and '--validate-output json', the results of each environment are printed after all
+   environments have finished, in order, as a JSON object like '{"environment": "dev", "validation": {...}}'.
+
+For example, a 'deploykf.yaml' file with two environments:
+
+    defaults:
+      source_version: 0.1.4
+      values:
+        - ./values/common.yaml
+      validate: true
+      kube_version: 1.26.0
+    environments:
+      dev:
+        values:
+          - ./values/dev.yaml
+        set:
+          deploykf_core.deploykf_auth.dex.expiry.idTokens: 24h
+        output_dir: ./GENERATOR_OUTPUT/dev
+      prod:
+        values:
+          - ./values/prod.yaml
+        output_dir: ./GENERATOR_OUTPUT/prod
+        scan_secrets: true
+        strict: true
+
+If a template fails to render, the error refers to the template by its path in the generator source:
+ - A few lines of the template are shown around the failing line.
+ - If a values key is missing, the full key is shown (for example, 'deploykf_core.deploykf_auth.admin_email').
+
+If '--report' is provided, a JSON report of the run is written to that file:
+ - The report contains the resolved source, values files, timings per phase, file counts, warnings and errors.
+ - The 'exit_code' field, and the 'code' of the final error, describe the category of any failure (see 'deploykf --help').
+ - Use '--output-format json' to print the same report to stdout (other results, like '--validate-output json', are then written to stderr).
+
+You may provide one or more '--only' and '--skip' patterns to render a subset of the generator templates:
+ - Patterns use '.gitignore' syntax, and are relative to the 'templates' folder of the generator source.
+ - A template is rendered if it matches any '--only' pattern (or none are provided), and does not match any '--skip' pattern.
+ - These patterns are applied on top of the generator's own '.gomplateignore' rules, they can't render an ignored template.
+ - The marker file records that the output is partial (with the patterns), so the next run only removes the files it lists.
+
+You may provide one or more '--keep' patterns to preserve paths in the '--output-dir':
+ - Patterns use '.gitignore' syntax, and are relative to the '--output-dir'.
+ - Patterns may also be listed in a '.deploykf_keep' file at the root of the '--output-dir'.
+ - Kept paths are not removed when cleaning, and are never overwritten by generated manifests.
+
+For safety, the '--output-dir' will NOT be cleaned if it is any of the following:
+ - the filesystem root, the user's home directory, or any ancestor of the current working directory
+ - the current working directory itself, unless it contains a '.deploykf_output' marker (so '-O .' works after the first run)
+ - a directory containing a '.git' entry (unless '.git' is kept with a '--keep' pattern)
+ - you may override these checks (except for the filesystem root) with '--allow-unsafe-output-dir'
+
 OUTPUT:
 ----------------
 
@@ -41,18 +170,32 @@ The '.deploykf_output' marker file contains the following information:
  - source_path: the path of the source artifact that was used 
  - source_hash: the SHA256 hash of the source artifact that was used
  - cli_version: the version of the deployKF CLI that was used
+ - output_files: the list of files that were generated (only these files are removed by the next run)
+ - partial, only, skip: if '--only' or '--skip' was provided, that the output is partial, and the patterns used
 
 EXAMPLES:
 ----------------
 
 To generate manifests from a GitHub source version:
 
-    $ deploykf generate --source-version v0.1.0 --values ./values.yaml --output-dir ./GENERATOR_OUTPUT
+    $ deploykf generate --source-version 0.1.0 --values ./values.yaml --output-dir ./GENERATOR_OUTPUT
 
 To generate manifests from a local source zip file:
 
     $ deploykf generate --source-path ./deploykf.zip --values ./values.yaml --output-dir ./GENERATOR_OUTPUT
 
+To print the manifests as a YAML stream, and pipe them into 'kubectl diff':
+
+    $ deploykf generate --source-version 0.1.0 --values ./values.yaml --output - | kubectl diff -f -
+
+To generate only the manifests for a single app (for debugging):
+
+    $ deploykf generate --source-version 0.1.0 --values ./values.yaml --output-dir ./GENERATOR_OUTPUT --only 'kubeflow-tools/pipelines/'
+
+To generate the manifests for the 'prod' environment of the project config file:
+
+    $ deploykf generate --env prod
+
 To generate manifests from a local source directory:
 
     $ deploykf generate --source-path ./deploykf --values ./values.yaml --output-dir ./GENERATOR_OUTPUT
@@ -63,9 +206,63 @@ type generateOptions struct {
 	sourcePath    string
 	values        []string
 	outputDir     string
+	outputArchive string
+	output        string
+	keep          []string
+	only          []string
+	skip          []string
+	allowUnsafe   bool
+	allowInvalid  bool
+	strict        bool
+
+	validate        bool
+	validateOutput  string
+	kubeVersion     string
+	schemaLocations []string
+
+	scanSecrets  bool
+	allowSecrets bool
+
+	reportPath   string
+	outputFormat string
+
+	watch         bool
+	watchInterval time.Duration
+	timeout       time.Duration
+
+	set       []string
+	setValues map[string]interface{}
+
+	configPath string
+	env        string
+	allEnvs    bool
+	parallel   int
+	targetName string
+
+	log   *logging.Logger
+	stdin *stdinBuffer
+}
+
+// stdinBuffer reads stdin at most once, so that `--values -` may be shared by every environment
+type stdinBuffer struct {
+	reader io.Reader
+	once   sync.Once
+	data   []byte
+	err    error
 }
 
-func newGenerateCmd(out io.Writer) *cobra.Command {
+// Reader returns a new reader of the data from stdin, which is read on the first call
+func (b *stdinBuffer) Reader() (io.Reader, error) {
+	b.once.Do(func() {
+		b.data, b.err = io.ReadAll(b.reader)
+	})
+	if b.err != nil {
+		return nil, exitcode.New(exitcode.KindInvalidValues, "failed to read values from stdin: %v", b.err)
+	}
+	return bytes.NewReader(b.data), nil
+}
+
+func newGenerateCmd(out io.Writer, g *globalOptions) *cobra.Command {
 	o := &generateOptions{}
 
 	var cmd = &cobra.Command{
@@ -73,224 +270,555 @@ func newGenerateCmd(out io.Writer) *cobra.Command {
 		Short: "Generate Kubernetes manifests from deployKF templates and config values",
 		Long:  generateHelp,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return o.run(out)
+			o.log = g.log()
+			o.stdin = &stdinBuffer{reader: cmd.InOrStdin()}
+			if o.env != "" || o.allEnvs {
+				return o.runEnvironments(cmd.Context(), cmd, out, cmd.ErrOrStderr())
+			}
+			return o.run(cmd.Context(), out, cmd.ErrOrStderr())
 		},
 	}
 
 	// add local flags
 	cmd.Flags().StringVarP(&o.sourceVersion, "source-version", "V", "", "a version tag from the 'deployKF/deployKF' GitHub repository")
 	cmd.Flags().StringVar(&o.sourcePath, "source-path", "", "a local path to a directory or '.zip' file containing a generator source")
-	cmd.Flags().StringSliceVarP(&o.values, "values", "f", []string{}, "a YAML file containing configuration values")
+	cmd.Flags().StringSliceVarP(&o.values, "values", "f", []string{}, "a YAML file containing configuration values ('-' for stdin, 'env:NAME', or an 'https://' or 'file://' URL)")
 	cmd.Flags().StringVarP(&o.outputDir, "output-dir", "O", "", "the output directory in which to generate the manifests")
+	cmd.Flags().StringVar(&o.outputArchive, "output-archive", "", "a '.tar.gz', '.tgz' or '.zip' file in which to package the generated manifests")
+	cmd.Flags().StringVar(&o.output, "output", "", "set to '-' to write the generated manifests to stdout as a multi-document YAML stream")
+	cmd.Flags().StringArrayVar(&o.set, "set", []string{}, "a 'key.path=value' override for a single value (takes precedence over all '--values' files)")
+	cmd.Flags().StringSliceVar(&o.keep, "keep", []string{}, "a '.gitignore' style pattern for paths in the output directory which should be preserved")
+	cmd.Flags().StringSliceVar(&o.only, "only", []string{}, "a '.gitignore' style pattern for template paths to render (all other templates are skipped)")
+	cmd.Flags().StringSliceVar(&o.skip, "skip", []string{}, "a '.gitignore' style pattern for template paths to skip")
+	cmd.Flags().BoolVar(&o.strict, "strict", false, "fail (instead of warning) if the values contain keys which are not in the 'default_values.yaml'")
+	cmd.Flags().BoolVar(&o.allowInvalid, "allow-invalid-yaml", false, "only warn (instead of failing) if any generated YAML files are invalid")
+	cmd.Flags().BoolVar(&o.validate, "validate", false, "validate the generated Kubernetes objects against their JSON schemas")
+	cmd.Flags().StringVar(&o.validateOutput, "validate-output", "text", "the format of the validation results, one of: 'text', 'json'")
+	cmd.Flags().StringVar(&o.kubeVersion, "kube-version", "master", "the Kubernetes version of the schemas to validate against")
+	cmd.Flags().StringSliceVar(&o.schemaLocations, "schema-location", []string{}, "a local directory containing JSON schemas for Kubernetes objects")
+	cmd.Flags().BoolVar(&o.scanSecrets, "scan-secrets", false, "scan the generated manifests for possible secret leaks")
+	cmd.Flags().BoolVar(&o.allowSecrets, "allow-secrets", false, "only warn (instead of failing) if '--scan-secrets' finds possible secret leaks")
+	cmd.Flags().StringVar(&o.reportPath, "report", "", "a file in which to write a JSON report of the run")
+	cmd.Flags().StringVar(&o.outputFormat, "output-format", "text", "the format of the command output, one of: 'text', 'json' (prints the JSON report to stdout)")
+	cmd.Flags().BoolVar(&o.watch, "watch", false, "watch the '--values' files and '--source-path' for changes, and re-generate the manifests")
+	cmd.Flags().DurationVar(&o.watchInterval, "watch-interval", 500*time.Millisecond, "how often '--watch' checks for changes")
+	cmd.Flags().DurationVar(&o.timeout, "timeout", 0, "the maximum duration of the generation, like '5m' (with '--watch', of each run), or 0 for no limit")
+	cmd.Flags().StringVar(&o.configPath, "config", project.DefaultConfigFile, "the project config file which defines the environments for '--env' and '--all-envs'")
+	cmd.Flags().StringVar(&o.env, "env", "", "generate the manifests for a single environment from the project config file")
+	cmd.Flags().BoolVar(&o.allEnvs, "all-envs", false, "generate the manifests for all environments from the project config file")
+	cmd.Flags().IntVar(&o.parallel, "parallel", 4, "the maximum number of environments to generate concurrently with '--all-envs' (templates are rendered one at a time)")
+	cmd.Flags().BoolVar(&o.allowUnsafe, "allow-unsafe-output-dir", false, "allow cleaning an output directory that would normally be refused (e.g. one containing '.git')")
 
 	// mark local flags
 	cmd.MarkFlagsMutuallyExclusive("source-version", "source-path")
-	cmd.MarkFlagRequired("output-dir")
+	cmd.MarkFlagsMutuallyExclusive("output-dir", "output-archive", "output")
+	cmd.MarkFlagsMutuallyExclusive("output-archive", "keep")
+	cmd.MarkFlagsMutuallyExclusive("output-archive", "allow-unsafe-output-dir")
+	cmd.MarkFlagsMutuallyExclusive("output", "keep")
+	cmd.MarkFlagsMutuallyExclusive("output", "allow-unsafe-output-dir")
+	cmd.MarkFlagsMutuallyExclusive("watch", "output-archive")
+	cmd.MarkFlagsMutuallyExclusive("watch", "output")
+	cmd.MarkFlagsMutuallyExclusive("env", "all-envs")
+	cmd.MarkFlagsMutuallyExclusive("all-envs", "watch")
+	cmd.MarkFlagsMutuallyExclusive("all-envs", "report")
+	cmd.MarkFlagsMutuallyExclusive("all-envs", "output-format")
 
 	return cmd
 }
 
-func (o *generateOptions) run(out io.Writer) error {
-	// TODO: verify the provided `--values`:
-	//  - check the YAML schema against a spec that is defined in the generator source
-	//  - check that all provided file paths exist (before gomplate fails)
+func (o *generateOptions) run(ctx context.Context, out io.Writer, errOut io.Writer) error {
+	err := o.validateFlags()
+	if err != nil {
+		return err
+	}
 
-	// initialise the source helper
-	// TODO: let users provide their own repo/owner for the source
-	sourceHelper := generate.NewSourceHelper()
+	// when stdout is used for data (manifests or a JSON report), other results are written to stderr
+	resultsOut := out
+	if o.output == "-" || o.outputFormat == "json" {
+		resultsOut = errOut
+	}
 
-	// create a temporary directory to store our generator source,
-	// and defer a function to clean it up after this function returns
-	tempSourcePath, err := os.MkdirTemp("", "deploykf-generator-source-*")
+	generatorOptions, err := o.generatorOptions(out, resultsOut)
+	if err != nil {
+		return err
+	}
+	g, err := generator.New(generatorOptions)
 	if err != nil {
-		fmt.Printf("Error creating temporary directory: %v\n", err)
-		return nil
+		return err
+	}
+
+	if o.watch {
+		return o.runWatch(ctx, g, out)
+	}
+	return o.runOnce(ctx, g, out)
+}
+
+// validateFlags verifies that the combination of flags is valid
+func (o *generateOptions) validateFlags() error {
+	// verify the generator source
+	if o.sourceVersion == "" && o.sourcePath == "" {
+		return exitcode.New(exitcode.KindInvalidFlags, "at least one of `--source-version` or `--source-path` must be provided")
+	}
+
+	// verify the output target
+	if o.outputDir == "" && o.outputArchive == "" && o.output == "" {
+		return exitcode.New(exitcode.KindInvalidFlags, "at least one of `--output-dir`, `--output-archive` or `--output` must be provided")
+	}
+	if o.output != "" && o.output != "-" {
+		return exitcode.New(exitcode.KindInvalidFlags, "the provided --output '%s' is not supported, only '-' (stdout) is allowed", o.output)
+	}
+	if o.outputArchive != "" && !generate.IsSupportedArchive(o.outputArchive) {
+		return exitcode.New(exitcode.KindInvalidFlags, "the provided --output-archive '%s' must end with '.tar.gz', '.tgz' or '.zip'", o.outputArchive)
+	}
+
+	// verify the output formats
+	if o.validateOutput != "text" && o.validateOutput != "json" {
+		return exitcode.New(exitcode.KindInvalidFlags, "the provided --validate-output '%s' is not supported, must be 'text' or 'json'", o.validateOutput)
+	}
+	if o.outputFormat != "text" && o.outputFormat != "json" {
+		return exitcode.New(exitcode.KindInvalidFlags, "the provided --output-format '%s' is not supported, must be 'text' or 'json'", o.outputFormat)
+	}
+	if o.outputFormat == "json" && o.output == "-" {
+		return exitcode.New(exitcode.KindInvalidFlags, "`--output-format json` can't be used with `--output -`, as both write to stdout")
+	}
+
+	// verify the watch options
+	if o.watch && o.outputDir == "" {
+		return exitcode.New(exitcode.KindInvalidFlags, "`--watch` requires `--output-dir`")
+	}
+	if o.watch && o.outputFormat == "json" {
+		return exitcode.New(exitcode.KindInvalidFlags, "`--watch` can't be used with `--output-format json`")
+	}
+	if o.watch && o.watchInterval <= 0 {
+		return exitcode.New(exitcode.KindInvalidFlags, "the provided --watch-interval '%s' must be positive", o.watchInterval)
+	}
+	if o.timeout < 0 {
+		return exitcode.New(exitcode.KindInvalidFlags, "the provided --timeout '%s' must not be negative", o.timeout)
 	}
-	defer func() {
-		err := os.RemoveAll(tempSourcePath)
+
+	return nil
+}
+
+// generatorOptions converts the flags into the options of a generator, which writes manifests streamed with
+// `--output -` to out, and the results of `--validate-output json` to resultsOut
+func (o *generateOptions) generatorOptions(out io.Writer, resultsOut io.Writer) (generator.Options, error) {
+	// parse the `--set` overrides, which take precedence over the set overrides from the project config
+	setValues := map[string]interface{}{}
+	for key, value := range o.setValues {
+		setValues[key] = value
+	}
+	for _, set := range o.set {
+		key, value, err := values.ParseSet(set)
 		if err != nil {
-			fmt.Printf("Error removing temporary directory: %v\n", err)
+			return generator.Options{}, exitcode.Wrap(exitcode.KindInvalidFlags, err)
 		}
-	}()
-
-	// populate the temporary directory with the generator source
-	//  - CASE 1: if `--source-version` is provided, download that version's `.zip` file and unzip it into the temp folder
-	//  - CASE 2: if `--source-path` points to a `.zip` file, unzip it into the temp folder
-	//  - CASE 3: if `--source-path` points to a folder, copy the contents of that folder into the temp folder
-	var sourcePath string
-	if o.sourceVersion != "" {
-		// CASE 1: download the source from GitHub
-		sourcePath, err = sourceHelper.DownloadAndUnpackSource(o.sourceVersion, tempSourcePath, out)
-		if err != nil {
-			return err
+		setValues[key] = value
+	}
+
+	// normalise the `--values` references, so stdin and environment variables are read before generating
+	valuesList, err := o.valuesList()
+	if err != nil {
+		return generator.Options{}, err
+	}
+
+	opts := generator.Options{
+		SourceVersion:        o.sourceVersion,
+		SourcePath:           o.sourcePath,
+		Values:               valuesList,
+		Set:                  setValues,
+		OutputDir:            o.outputDir,
+		OutputArchive:        o.outputArchive,
+		Keep:                 o.keep,
+		Only:                 o.only,
+		Skip:                 o.skip,
+		AllowUnsafeOutputDir: o.allowUnsafe,
+		StrictValues:         o.strict,
+		AllowInvalidYAML:     o.allowInvalid,
+		Validate:             o.validate,
+		KubeVersion:          o.kubeVersion,
+		SchemaLocations:      o.schemaLocations,
+		ScanSecrets:          o.scanSecrets,
+		AllowSecrets:         o.allowSecrets,
+		Logger:               o.log,
+	}
+	if o.output == "-" {
+		opts.OutputStream = out
+	}
+	if o.validateOutput == "json" {
+		opts.ValidationOutput = resultsOut
+	}
+	return opts, nil
+}
+
+// valuesList converts the `--values` references into generator values, in order of increasing precedence
+func (o *generateOptions) valuesList() ([]generator.Values, error) {
+	var stdin io.Reader
+	stdinCount := 0
+	for _, ref := range o.values {
+		if ref == "-" {
+			stdinCount++
 		}
-	} else if o.sourcePath != "" {
-		sourcePath, err = filepath.EvalSymlinks(o.sourcePath)
+	}
+	if stdinCount > 1 {
+		return nil, exitcode.New(exitcode.KindInvalidFlags, "`--values -` can only be used once, as stdin can only be read once")
+	}
+	if stdinCount == 1 && o.stdin != nil {
+		var err error
+		stdin, err = o.stdin.Reader()
 		if err != nil {
-			if os.IsNotExist(err) {
-				return fmt.Errorf("the provided --source-path '%s' does not exist", o.sourcePath)
-			}
-			return err
+			return nil, err
 		}
-		sourceIsDir, sourceIsFile, err := generate.PathExists(sourcePath)
+	}
+
+	valuesList := make([]generator.Values, 0, len(o.values))
+	for _, ref := range o.values {
+		v, err := generator.ValuesFromRef(ref, stdin)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		if sourceIsFile && strings.HasSuffix(sourcePath, ".zip") {
-			// CASE 2: source is a .zip file
-			fmt.Fprintf(out, "Using custom source file: %s\n", o.sourcePath)
-			err := generate.UnzipFile(sourcePath, tempSourcePath, "generator")
-			if err != nil {
-				return err
-			}
-		} else if sourceIsDir {
-			// CASE 3: source is a folder
-			fmt.Fprintf(out, "Using custom source folder: %s\n", o.sourcePath)
-			err := generate.CopyFolder(sourcePath, tempSourcePath)
-			if err != nil {
-				return err
-			}
-		} else {
-			return fmt.Errorf("the provided --source-path '%s' must be a folder or a .zip file", o.sourcePath)
+		valuesList = append(valuesList, v)
+	}
+	return valuesList, nil
+}
+
+// runOnce runs the generator a single time, and writes the report (if requested)
+func (o *generateOptions) runOnce(ctx context.Context, g *generator.Generator, out io.Writer) error {
+	ctx, cancel := o.withTimeout(ctx)
+	defer cancel()
+	report, err := g.Generate(ctx)
+
+	// write the report to `--report` (if requested)
+	if o.reportPath != "" {
+		o.log.Debugf("writing report: %s", o.reportPath)
+		reportErr := report.WriteFile(o.reportPath)
+		if reportErr != nil {
+			o.log.Errorf("failed to write report '%s': %v", o.reportPath, reportErr)
 		}
-	} else {
-		return fmt.Errorf("at least one of `--source-version` or `--source-path` must be provided")
 	}
 
-	// important paths from the generator source
-	templatesPath := filepath.Join(tempSourcePath, "templates")
-	helpersPath := filepath.Join(tempSourcePath, "helpers")
-	defaultValuesPath := filepath.Join(tempSourcePath, "default_values.yaml")
-	markerPath := filepath.Join(tempSourcePath, ".deploykf_generator")
+	// print the report to stdout (if requested)
+	if o.outputFormat == "json" {
+		data, reportErr := report.JSON()
+		if reportErr != nil {
+			return reportErr
+		}
+		fmt.Fprintln(out, string(data))
+	}
 
-	// verify the generator source is valid, and is supported by this version of the CLI
-	err = generate.VerifyGeneratorSource(templatesPath, helpersPath, defaultValuesPath, markerPath)
-	if err != nil {
-		return err
+	return err
+}
+
+// withTimeout returns a copy of the context which is cancelled after the `--timeout` (if any)
+func (o *generateOptions) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if o.timeout <= 0 {
+		return context.WithCancel(ctx)
 	}
+	return context.WithTimeout(ctx, o.timeout)
+}
 
-	// write runtime config templates
-	//  - note, we are writing these files into the source folder, not the output folder
-	runtimePath := filepath.Join(tempSourcePath, "runtime")
-	err = generate.WriteRuntimeTemplates(runtimePath, templatesPath, o.outputDir)
-	if err != nil {
-		return err
+// runEnvironments generates the manifests for the `--env` (or `--all-envs`) from the project config file
+func (o *generateOptions) runEnvironments(ctx context.Context, cmd *cobra.Command, out io.Writer, errOut io.Writer) error {
+	// the project config defines these flags for each environment
+	for _, name := range []string{"source-version", "source-path", "values", "output-dir", "output-archive", "output"} {
+		if cmd.Flags().Changed(name) {
+			return exitcode.New(exitcode.KindInvalidFlags, "`--%s` can't be used with `--env` or `--all-envs`, it is set by the project config", name)
+		}
 	}
 
-	// GENERATOR PHASE 1: render `.gomplateignore_template` files
-	//  - note, we are rendering the `.gomplateignore` files into the generator source
-	//    templates folder, not the output folder
-	phase1Config := &gomplate.Config{ //nolint:staticcheck
-		InputDir:      templatesPath,
-		OutputMap:     templatesPath + `/{{< .in | strings.ReplaceAll ".gomplateignore_template" ".gomplateignore" >}}`,
-		ExcludeGlob:   []string{"*", "!*.gomplateignore_template"},
-		LDelim:        "{{<",
-		RDelim:        ">}}",
-		DataSources:   o.gomplateDataSources(),
-		Contexts:      o.gomplateContexts(defaultValuesPath),
-		Templates:     o.gomplateTemplates(helpersPath, runtimePath),
-		SuppressEmpty: true,
-	}
-	err = gomplate.RunTemplates(phase1Config) //nolint:staticcheck
+	config, err := project.Load(o.configPath)
 	if err != nil {
-		return err
+		return exitcode.Wrap(exitcode.KindInvalidConfig, err)
 	}
 
-	// clean the `--output-dir` if it's safe to do so
-	err = generate.CleanOutputDirectory(o.outputDir)
-	if err != nil {
-		return err
+	// generate a single environment
+	if !o.allEnvs {
+		env, err := config.Environment(o.env)
+		if err != nil {
+			return exitcode.Wrap(exitcode.KindInvalidConfig, err)
+		}
+		envOptions := o.withEnvironment(env, cmd.Flags().Changed("kube-version"))
+		envOptions.log = o.log.With("env", o.env)
+		envOptions.log.Infof("Generating environment: %s", o.env)
+		return envOptions.run(ctx, out, errOut)
 	}
 
-	// calculate the hash of the generator source
-	//  - if the source was a `.zip` file, we'll use the hash of the file
-	//  - if the source was a folder, we'll use the hash of the folder
-	//    note, we'll ignore the `.gomplateignore` files when calculating the hash
-	//    see `generate.HashPath` for more details
-	sourceArtifactHash, err := generate.HashPath(sourcePath, []string{".gomplateignore"})
-	if err != nil {
-		return err
+	// generate all environments
+	if o.parallel < 1 {
+		return exitcode.New(exitcode.KindInvalidFlags, "the provided --parallel '%d' must be at least 1", o.parallel)
+	}
+	var targets []*generateOptions
+	for _, envName := range config.EnvironmentNames() {
+		env, err := config.Environment(envName)
+		if err != nil {
+			return exitcode.Wrap(exitcode.KindInvalidConfig, err)
+		}
+		envOptions := o.withEnvironment(env, cmd.Flags().Changed("kube-version"))
+		envOptions.targetName = envName
+		envOptions.log = o.log.With("env", envName)
+		err = envOptions.validateFlags()
+		if err != nil {
+			return fmt.Errorf("invalid environment '%s': %w", envName, err)
+		}
+		targets = append(targets, envOptions)
 	}
+	return o.runTargets(ctx, targets, out)
+}
 
-	// create marker file in the `--output-dir`
-	//  - will create the directory if it doesn't already exist
-	//  - the marker will contain JSON with information like run time and source version
-	err = generate.CreateMarkerFile(o.outputDir, o.sourceVersion, sourcePath, sourceArtifactHash, version.GetVersion())
-	if err != nil {
-		return err
+// runTargets generates the manifests for many targets, acquiring each distinct generator source only once,
+// then rendering up to `--parallel` targets concurrently
+func (o *generateOptions) runTargets(ctx context.Context, targets []*generateOptions, out io.Writer) error {
+	ctx, cancel := o.withTimeout(ctx)
+	defer cancel()
+
+	// verify the inputs of every target, before acquiring any sources
+	//  - note, the results of each target are buffered, so they can be printed in order (see `writeTargetResults`)
+	targetOptions := make([]generator.Options, len(targets))
+	targetResults := make([]*bytes.Buffer, len(targets))
+	for i, target := range targets {
+		targetResults[i] = &bytes.Buffer{}
+		generatorOptions, err := target.generatorOptions(out, targetResults[i])
+		if err != nil {
+			return fmt.Errorf("failed to generate environment '%s': %w", target.targetName, err)
+		}
+		g, err := generator.New(generatorOptions)
+		if err == nil {
+			err = g.CheckInputs()
+		}
+		if err != nil {
+			return fmt.Errorf("failed to generate environment '%s': %w", target.targetName, err)
+		}
+		targetOptions[i] = generatorOptions
 	}
 
-	// GENERATOR PHASE 2: render to the output folder
-	phase2Config := &gomplate.Config{ //nolint:staticcheck
-		InputDir:      templatesPath,
-		OutputDir:     o.outputDir,
-		LDelim:        "{{<",
-		RDelim:        ">}}",
-		DataSources:   o.gomplateDataSources(),
-		Contexts:      o.gomplateContexts(defaultValuesPath),
-		Templates:     o.gomplateTemplates(helpersPath, runtimePath),
-		SuppressEmpty: true,
+	// acquire each distinct generator source once, and create the generator of each target from it
+	//  - note, the sources are removed after all targets have finished
+	sources := map[string]*generator.Source{}
+	generators := make([]*generator.Generator, len(targets))
+	for i, target := range targets {
+		sourceKey := target.sourceVersion + "\x00" + target.sourcePath
+		source, ok := sources[sourceKey]
+		if !ok {
+			var err error
+			source, err = generator.OpenSource(ctx, generator.SourceOptions{Version: target.sourceVersion, Path: target.sourcePath, Logger: target.log})
+			if err != nil {
+				return fmt.Errorf("failed to generate environment '%s': %w", target.targetName, err)
+			}
+			defer func() {
+				err := source.Close()
+				if err != nil {
+					o.log.Warnf("%v", err)
+				}
+			}()
+			sources[sourceKey] = source
+		}
+
+		generatorOptions := targetOptions[i]
+		generatorOptions.SourceVersion = ""
+		generatorOptions.SourcePath = ""
+		generatorOptions.Source = source
+		g, err := generator.New(generatorOptions)
+		if err != nil {
+			return fmt.Errorf("failed to generate environment '%s': %w", target.targetName, err)
+		}
+		generators[i] = g
 	}
-	err = gomplate.RunTemplates(phase2Config) //nolint:staticcheck
+	o.log.Infof("Generating %d environments from %d sources (parallel: %d)", len(targets), len(sources), o.parallel)
+
+	// render the targets with a bounded pool of workers
+	targetErrors := make([]error, len(targets))
+	semaphore := make(chan struct{}, o.parallel)
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target *generateOptions) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			target.log.Infof("Generating environment: %s", target.targetName)
+			_, err := generators[i].Generate(ctx)
+			if err != nil {
+				target.log.Errorf("%v", err)
+			}
+			targetErrors[i] = err
+		}(i, target)
+	}
+	wg.Wait()
+
+	err := writeTargetResults(out, targets, targetResults)
 	if err != nil {
 		return err
 	}
 
-	// log the output directory
-	fmt.Fprintf(out, "Generated manifests at: %s\n", o.outputDir)
-
+	// summarise the failed targets
+	var failedNames []string
+	var firstErr error
+	for i, err := range targetErrors {
+		if err != nil {
+			failedNames = append(failedNames, targets[i].targetName)
+			if firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	if firstErr != nil {
+		return exitcode.New(exitcode.KindOf(firstErr), "failed to generate %d of %d environments: %s", len(failedNames), len(targets), strings.Join(failedNames, ", "))
+	}
 	return nil
 }
 
-// build the `DataSources` for our `gomplate.Config`
-func (o *generateOptions) gomplateDataSources() []string {
-
-	// create DataSource for each `--values` provided by the user
-	values := o.values
-	dataSources := make([]string, len(values))
-	for i, v := range values {
-		dataSources[i] = "Values_" + strconv.Itoa(i) + "=" + v
+// writeTargetResults writes the buffered results of each target (like `--validate-output json`) in order,
+// wrapping each in a JSON object with the name of its environment
+func writeTargetResults(out io.Writer, targets []*generateOptions, targetResults []*bytes.Buffer) error {
+	for i, target := range targets {
+		if targetResults[i].Len() == 0 {
+			continue
+		}
+		data, err := json.MarshalIndent(struct {
+			Environment string          `json:"environment"`
+			Validation  json.RawMessage `json:"validation"`
+		}{
+			Environment: target.targetName,
+			Validation:  targetResults[i].Bytes(),
+		}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to write the results of environment '%s': %w", target.targetName, err)
+		}
+		fmt.Fprintln(out, string(data))
 	}
+	return nil
+}
 
-	return dataSources
+// withEnvironment returns a copy of the options, with the settings from a project config environment applied
+func (o *generateOptions) withEnvironment(env *project.Environment, kubeVersionChanged bool) *generateOptions {
+	envOptions := *o
+	envOptions.sourceVersion = env.SourceVersion
+	envOptions.sourcePath = env.SourcePath
+	envOptions.values = env.Values
+	envOptions.setValues = env.Set
+	envOptions.outputDir = env.OutputDir
+	envOptions.keep = append(append([]string{}, env.Keep...), o.keep...)
+	envOptions.schemaLocations = append(append([]string{}, env.SchemaLocations...), o.schemaLocations...)
+
+	// flags which enable checks can only add to the environment settings
+	if env.Validate != nil {
+		envOptions.validate = o.validate || *env.Validate
+	}
+	if env.ScanSecrets != nil {
+		envOptions.scanSecrets = o.scanSecrets || *env.ScanSecrets
+	}
+	if env.Strict != nil {
+		envOptions.strict = o.strict || *env.Strict
+	}
+	if env.KubeVersion != "" && !kubeVersionChanged {
+		envOptions.kubeVersion = env.KubeVersion
+	}
+	return &envOptions
 }
 
-// build the `Contexts` for our `gomplate.Config`
-func (o *generateOptions) gomplateContexts(defaultValuesPath string) []string {
-	var sb strings.Builder
-
-	// merge the user-provided `--values` DataSources by placing a "|" between them
-	// NOTE: merges happen from right to left, so we add the `--values` in reverse
-	//       order so the `--values` that were provided later take precedence
-	values := o.values
-	for i := len(values) - 1; i >= 0; i-- {
-		if sb.Len() > 0 {
-			sb.WriteString("|")
+// runWatch runs the generator, then re-runs it every time the `--values` files or `--source-path` change,
+// until the context is cancelled (when the process is interrupted)
+//   - note, only local values files are watched (not stdin, environment variables or 'https://' URLs)
+func (o *generateOptions) runWatch(ctx context.Context, g *generator.Generator, out io.Writer) error {
+	var watchedPaths []string
+	for _, v := range g.Values() {
+		if v.Path() != "" {
+			watchedPaths = append(watchedPaths, v.Path())
 		}
-		sb.WriteString("Values_")
-		sb.WriteString(strconv.Itoa(i))
 	}
-
-	// add the `default_values.yaml` to the end of the merge
-	if sb.Len() > 0 {
-		sb.WriteString("|")
+	if o.sourcePath != "" {
+		watchedPaths = append(watchedPaths, o.sourcePath)
+	}
+	if len(watchedPaths) == 0 {
+		return exitcode.New(exitcode.KindInvalidFlags, "`--watch` requires `--source-path` or at least one local `--values` file")
 	}
-	sb.WriteString(defaultValuesPath)
 
-	// we only use `merge` if there is more than one DataSource
-	valuesContext := "Values="
-	if len(values) > 0 {
-		valuesContext += "merge:"
+	// take the snapshot before the first run, so changes made during the run are detected
+	snapshot, err := generate.TakeFileSnapshot(watchedPaths)
+	if err != nil {
+		return err
+	}
+	outputHashes, err := generate.HashFiles(o.outputDir)
+	if err != nil {
+		return err
 	}
-	valuesContext += sb.String()
+	outputHashes = o.runWatchCycle(ctx, g, out, outputHashes)
+	o.log.Infof("Watching for changes in: %s (press Ctrl+C to stop)", strings.Join(watchedPaths, ", "))
+
+	ticker := time.NewTicker(o.watchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			o.log.Infof("Stopped watching for changes")
+			return nil
+		case <-ticker.C:
+		}
 
-	return []string{valuesContext}
+		current, err := generate.TakeFileSnapshot(watchedPaths)
+		if err != nil {
+			return err
+		}
+		changedPaths := current.ChangedPaths(snapshot)
+		if len(changedPaths) == 0 {
+			continue
+		}
+
+		// debounce, by waiting until the watched paths stop changing for a full interval
+		//  - note, editors often write a file in several steps when saving
+		for {
+			select {
+			case <-ctx.Done():
+				o.log.Infof("Stopped watching for changes")
+				return nil
+			case <-ticker.C:
+			}
+			next, err := generate.TakeFileSnapshot(watchedPaths)
+			if err != nil {
+				return err
+			}
+			if len(next.ChangedPaths(current)) == 0 {
+				break
+			}
+			changedPaths = next.ChangedPaths(snapshot)
+			current = next
+		}
+		snapshot = current
+
+		o.log.Infof("Detected changes in: %s", strings.Join(changedPaths, ", "))
+		outputHashes = o.runWatchCycle(ctx, g, out, outputHashes)
+	}
 }
 
-// build the `Templates` for our `gomplate.Config`
-func (o *generateOptions) gomplateTemplates(helpersPath string, runtimePath string) []string {
-	return []string{
-		"helpers=" + helpersPath,
-		"runtime=" + runtimePath,
+// runWatchCycle runs the generator once, and logs a summary of the output files which changed since the
+// last successful run (described by its output file hashes), then returns the new output file hashes.
+// Errors are logged (rather than returned), so that watching continues until the problem is fixed.
+func (o *generateOptions) runWatchCycle(ctx context.Context, g *generator.Generator, out io.Writer, before map[string]string) map[string]string {
+	err := o.runOnce(ctx, g, out)
+	if err != nil {
+		o.log.Errorf("%v", err)
+		return before
+	}
+
+	after, err := generate.HashFiles(o.outputDir)
+	if err != nil {
+		o.log.Errorf("failed to read output directory: %v", err)
+		return before
+	}
+	changes := generate.DiffFileHashes(before, after)
+	if changes.IsEmpty() {
+		o.log.Infof("No output files changed")
+		return after
+	}
+	o.log.Infof("Changed output files: %d added, %d modified, %d removed", len(changes.Added), len(changes.Modified), len(changes.Removed))
+	for _, relPath := range changes.Added {
+		o.log.Infof("  + %s", relPath)
+	}
+	for _, relPath := range changes.Modified {
+		o.log.Infof("  ~ %s", relPath)
+	}
+	for _, relPath := range changes.Removed {
+		o.log.Infof("  - %s", relPath)
 	}
+	return after
 }
@@ -1,17 +1,26 @@
 package deploykf
 
 import (
+	"context"
 	"io"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
+
+	"github.com/deployKF/cli/internal/exitcode"
+	"github.com/deployKF/cli/internal/logging"
 )
 
 const rootHelp = `deployKF is your open-source helper for deploying MLOps tools on Kubernetes.
 
 Common actions for deployKF:
 
-- deploykf generate:  Generate Kubernetes manifests from deployKF templates and config values
+- deploykf generate:        Generate Kubernetes manifests from deployKF templates and config values
+- deploykf values init:     Create a starter values file with the most commonly changed keys
+- deploykf values explain:  Describe a key of the default values, with its comments, type and default
+- deploykf values migrate:  Update a values file from one version of deployKF to another
 
 The default directories depend on the Operating System. The defaults are listed below:
 
@@ -20,19 +29,107 @@ The default directories depend on the Operating System. The defaults are listed
 | Linux            | $HOME/.deploykf/assets         |
 | macOS            | $HOME/.deploykf/assets         |
 | Windows          | %userprofile%\.deploykf\assets |
+
+The exit code of a command describes the category of any error:
+
+| Exit Code | Category           | Description                                                           |
+|-----------|--------------------|-----------------------------------------------------------------------|
+| 0         |                    | success                                                               |
+| 1         | error              | an error without a more specific category                             |
+| 2         | invalid_flags      | the command-line flags or arguments are invalid                       |
+| 3         | source_not_found   | the generator source does not exist                                   |
+| 4         | download_failed    | the generator source could not be downloaded                          |
+| 5         | unsupported_source | the generator source is invalid, or its schema version is unsupported |
+| 6         | invalid_values     | the values files could not be read or are invalid                     |
+| 7         | render_failed      | the templates could not be rendered                                   |
+| 8         | unsafe_output_dir  | the output directory is not safe to clean                             |
+| 9         | check_failed       | the generated manifests failed a check (like '--validate')            |
+| 10        | invalid_config     | the project config file could not be read or is invalid               |
+| 11        | canceled           | the command was interrupted (e.g. by Ctrl+C), or reached its timeout  |
+
+Log messages are written to stderr, so that stdout only contains the output of commands:
+ - Use '--log-level' to choose the minimum level of messages ('debug', 'info', 'warn', 'error').
+ - Use '-v' as a shorthand for '--log-level debug', and '-q' for '--log-level error'.
+ - Use '--log-format json' to write each message as a JSON object (for example, to ingest logs in CI).
+
+If a command is interrupted (by Ctrl+C or SIGTERM), it stops at the next safe point and removes its temporary files:
+ - Press Ctrl+C a second time to exit immediately (temporary files may be left behind).
 `
 
+// globalOptions are the options which are shared by all commands
+type globalOptions struct {
+	logLevel  string
+	logFormat string
+	verbose   bool
+	quiet     bool
+
+	logger *logging.Logger
+}
+
+// initLogger creates the logger from the global flags, writing to errOut
+func (g *globalOptions) initLogger(errOut io.Writer) error {
+	if g.verbose && g.quiet {
+		return exitcode.New(exitcode.KindInvalidFlags, "`-v` and `-q` can't be used together")
+	}
+	level, err := logging.ParseLevel(g.logLevel)
+	if err != nil {
+		return exitcode.Wrap(exitcode.KindInvalidFlags, err)
+	}
+	if g.verbose {
+		level = logging.LevelDebug
+	}
+	if g.quiet {
+		level = logging.LevelError
+	}
+	g.logger, err = logging.New(errOut, level, g.logFormat)
+	return exitcode.Wrap(exitcode.KindInvalidFlags, err)
+}
+
+// log returns the logger, which discards all messages if it was not initialised
+func (g *globalOptions) log() *logging.Logger {
+	if g.logger == nil {
+		return logging.Discard()
+	}
+	return g.logger
+}
+
 func newRootCmd(out io.Writer) *cobra.Command {
+	g := &globalOptions{}
+
 	var cmd = &cobra.Command{
 		Use:          "deploykf",
 		Short:        "deployKF is your open-source helper for deploying MLOps tools on Kubernetes",
 		Long:         rootHelp,
 		SilenceUsage: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// NOTE: cobra validates flag groups after this hook, so we validate them here to give the errors a Kind
+			err := cmd.ValidateRequiredFlags()
+			if err != nil {
+				return exitcode.Wrap(exitcode.KindInvalidFlags, err)
+			}
+			err = cmd.ValidateFlagGroups()
+			if err != nil {
+				return exitcode.Wrap(exitcode.KindInvalidFlags, err)
+			}
+			return g.initLogger(cmd.ErrOrStderr())
+		},
 	}
 
+	// errors from parsing flags are always invalid flags
+	cmd.SetFlagErrorFunc(func(cmd *cobra.Command, err error) error {
+		return exitcode.Wrap(exitcode.KindInvalidFlags, err)
+	})
+
+	// add global flags
+	cmd.PersistentFlags().StringVar(&g.logLevel, "log-level", "info", "the minimum level of log messages, one of: 'debug', 'info', 'warn', 'error'")
+	cmd.PersistentFlags().StringVar(&g.logFormat, "log-format", logging.FormatText, "the format of log messages, one of: 'text', 'json'")
+	cmd.PersistentFlags().BoolVarP(&g.verbose, "verbose", "v", false, "show debug log messages (same as '--log-level debug')")
+	cmd.PersistentFlags().BoolVarP(&g.quiet, "quiet", "q", false, "only show error log messages (same as '--log-level error')")
+
 	// add subcommands
 	cmd.AddCommand(
-		newGenerateCmd(out),
+		newGenerateCmd(out, g),
+		newValuesCmd(out, g),
 		newVersionCmd(out),
 	)
 
@@ -41,9 +138,18 @@ func newRootCmd(out io.Writer) *cobra.Command {
 
 // Execute instantiates and runs root command, this is called by main.main()
 func Execute() {
+	// cancel the context on the first SIGINT or SIGTERM, so that commands can stop and clean up
+	//  - note, we stop handling signals once the context is cancelled, so a second signal exits immediately
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+
 	rootCmd := newRootCmd(os.Stdout)
-	err := rootCmd.Execute()
+	err := rootCmd.ExecuteContext(ctx)
 	if err != nil {
-		os.Exit(1)
+		os.Exit(exitcode.Code(err))
 	}
 }
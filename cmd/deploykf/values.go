@@ -0,0 +1,634 @@
+package deploykf
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/deployKF/cli/internal/exitcode"
+	"github.com/deployKF/cli/internal/generate"
+	"github.com/deployKF/cli/internal/logging"
+	"github.com/deployKF/cli/internal/require"
+	"github.com/deployKF/cli/internal/schema"
+	"github.com/deployKF/cli/internal/sops"
+	"github.com/deployKF/cli/internal/values"
+	"github.com/deployKF/cli/pkg/generator"
+)
+
+const valuesHelp = `These commands help you write and understand the values files of deployKF.
+`
+
+const valuesInitHelp = `This command will create a starter values file, from the 'default_values.yaml' of a generator source.
+
+The starter values file only contains the most commonly changed keys (like the domain, TLS and authentication),
+and keeps the comments which describe them in the 'default_values.yaml':
+ - Keys which don't exist in the chosen generator source are left out.
+ - Use '--key' to include more keys (selecting a mapping includes all of its keys).
+
+If '--interactive' is provided, you will be asked for the domain, TLS and authentication settings:
+ - Press Enter to keep the default value (shown in brackets).
+ - The questions are written to stderr, so the values file may still be written to stdout.
+
+EXAMPLES:
+----------------
+
+Create a starter values file for a specific version of deployKF:
+
+    $ deploykf values init --source-version 0.1.4 --output ./values.yaml
+
+Answer questions about the most important settings:
+
+    $ deploykf values init --source-version 0.1.4 --output ./values.yaml --interactive
+`
+
+const valuesExplainHelp = `This command will describe a key of the 'default_values.yaml' of a generator source (like 'kubectl explain').
+
+The key is a dot-separated path (like 'deploykf_core.deploykf_auth.dex'), and the description includes:
+ - The comments above (or beside) the key in the 'default_values.yaml'.
+ - The type of the key, and its default value (for keys which are not a map).
+ - The keys below it (for keys which are a map), with the first line of their comments.
+ - If the generator source has a 'values_schema.json', its description, type, allowed values and flags for the key.
+
+If the key does not exist, the keys of its longest existing parent are listed.
+
+EXAMPLES:
+----------------
+
+Describe the settings of Dex:
+
+    $ deploykf values explain deploykf_core.deploykf_auth.dex --source-version 0.1.4
+
+List the top-level keys of a local generator source:
+
+    $ deploykf values explain deploykf_core --source-path ./generator
+`
+
+const valuesMigrateHelp = `This command will update a values file from one version of deployKF to another.
+
+The migration rules are read from the 'values_migrations.yaml' of the generator source for the '--to' version:
+ - If '--source-path' is provided, the rules are read from that generator source instead.
+ - Rules are applied one version at a time, so '--from 0.1.2 --to 0.1.4' applies the rules of 0.1.3 and then 0.1.4.
+ - Versions may have a 'v' prefix (so 'v0.1.4' is the same as '0.1.4').
+
+The rules may rename, move or remove keys, or describe changes to default values:
+ - Renamed, moved and removed keys are updated in the values file (its comments are kept).
+ - A moved key is appended to the end of its new parent, other keys keep their order.
+ - The values file is re-written with a 2-space indent, empty lines are only kept between top-level keys,
+   and line comments are separated by a single space (quoted strings and flow style like '[a, b]' are kept).
+ - If a changed default is not set in the values file, you are told how to keep the previous behavior.
+ - If a key can't be updated (for example, both its old and new paths are set), it is left as it is.
+ - Changes which need manual attention are logged as warnings (the updated values file is still written).
+
+Values files which are encrypted with SOPS must be decrypted first (updating them would invalidate their signature).
+
+EXAMPLES:
+----------------
+
+Update a values file from 0.1.3 to 0.1.4, and write it to a new file:
+
+    $ deploykf values migrate --from 0.1.3 --to 0.1.4 -f ./values.yaml --output ./values-0.1.4.yaml
+
+Update a values file in place:
+
+    $ deploykf values migrate --from 0.1.3 --to 0.1.4 -f ./values.yaml --output ./values.yaml --force
+`
+
+// migrateSourceVersion returns the generator source version for a `--to` version, which may have a "v" prefix
+//   - note, source versions don't have a "v" prefix (it is added to the release tag when downloading)
+func migrateSourceVersion(to string) string {
+	return strings.TrimPrefix(strings.TrimSpace(to), "v")
+}
+
+// commonValuesKeys are the keys which are most commonly changed from their defaults, in the starter values file
+var commonValuesKeys = []string{
+	"argocd.source.repo",
+	"deploykf_core.deploykf_istio_gateway.gateway.hostname",
+	"deploykf_core.deploykf_istio_gateway.gateway.ports",
+	"deploykf_core.deploykf_istio_gateway.gateway.tls",
+	"deploykf_dependencies.cert_manager.clusterIssuer",
+	"deploykf_core.deploykf_auth.admin_email",
+	"deploykf_core.deploykf_auth.dex.staticPasswords",
+	"deploykf_core.deploykf_auth.dex.connectors",
+	"deploykf_core.deploykf_profiles_generator.users",
+	"deploykf_core.deploykf_profiles_generator.profiles",
+}
+
+// valuesPrompt is a question for `deploykf values init --interactive`, which sets a scalar value
+type valuesPrompt struct {
+	keyPath  string
+	question string
+	boolean  bool
+}
+
+// valuesInitPrompts are the questions of `deploykf values init --interactive`
+//   - note, questions for keys which don't exist in the generator source are skipped
+var valuesInitPrompts = []valuesPrompt{
+	{keyPath: "deploykf_core.deploykf_istio_gateway.gateway.hostname", question: "Domain of the deployKF gateway"},
+	{keyPath: "deploykf_core.deploykf_istio_gateway.gateway.tls.enabled", question: "Serve the gateway with TLS (HTTPS)", boolean: true},
+	{keyPath: "deploykf_dependencies.cert_manager.clusterIssuer.enabled", question: "Issue TLS certificates with the default cert-manager ClusterIssuer", boolean: true},
+	{keyPath: "deploykf_core.deploykf_auth.admin_email", question: "Email of the deployKF admin user"},
+}
+
+func newValuesCmd(out io.Writer, g *globalOptions) *cobra.Command {
+	var cmd = &cobra.Command{
+		Use:   "values",
+		Short: "Create and understand deployKF values files",
+		Long:  valuesHelp,
+		Args:  require.NoArgs,
+	}
+
+	// add subcommands
+	cmd.AddCommand(
+		newValuesInitCmd(out, g),
+		newValuesExplainCmd(out, g),
+		newValuesMigrateCmd(out, g),
+	)
+
+	return cmd
+}
+
+type valuesInitOptions struct {
+	sourceVersion string
+	sourcePath    string
+	output        string
+	force         bool
+	keys          []string
+	interactive   bool
+
+	log *logging.Logger
+}
+
+func newValuesInitCmd(out io.Writer, g *globalOptions) *cobra.Command {
+	o := &valuesInitOptions{}
+
+	var cmd = &cobra.Command{
+		Use:   "init",
+		Short: "Create a starter values file with the most commonly changed keys",
+		Long:  valuesInitHelp,
+		Args:  require.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.log = g.log()
+			return o.run(cmd.Context(), out, cmd.InOrStdin(), cmd.ErrOrStderr())
+		},
+	}
+
+	// add local flags
+	cmd.Flags().StringVarP(&o.sourceVersion, "source-version", "V", "", "a version tag from the 'deployKF/deployKF' GitHub repository")
+	cmd.Flags().StringVar(&o.sourcePath, "source-path", "", "a local path to a directory or '.zip' file containing a generator source")
+	cmd.Flags().StringVarP(&o.output, "output", "o", "-", "the file in which to write the starter values, or '-' for stdout")
+	cmd.Flags().BoolVar(&o.force, "force", false, "overwrite the '--output' file if it already exists")
+	cmd.Flags().StringSliceVar(&o.keys, "key", []string{}, "an additional dot-separated key (like 'kubeflow_tools.pipelines') to include in the starter values")
+	cmd.Flags().BoolVarP(&o.interactive, "interactive", "i", false, "ask for the domain, TLS and authentication settings")
+
+	// mark local flags
+	cmd.MarkFlagsMutuallyExclusive("source-version", "source-path")
+
+	return cmd
+}
+
+func (o *valuesInitOptions) run(ctx context.Context, out io.Writer, in io.Reader, errOut io.Writer) error {
+	// verify the flags, before acquiring the source
+	if o.sourceVersion == "" && o.sourcePath == "" {
+		return exitcode.New(exitcode.KindInvalidFlags, "at least one of `--source-version` or `--source-path` must be provided")
+	}
+	if o.output != "-" && !o.force {
+		outputIsDir, outputIsFile, err := generate.PathExists(o.output)
+		if err != nil {
+			return err
+		}
+		if outputIsDir || outputIsFile {
+			return exitcode.New(exitcode.KindInvalidFlags, "the provided --output '%s' already exists (use --force to overwrite it)", o.output)
+		}
+	}
+
+	source, err := generator.OpenSource(ctx, generator.SourceOptions{Version: o.sourceVersion, Path: o.sourcePath, Logger: o.log})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := source.Close()
+		if err != nil {
+			o.log.Warnf("%v", err)
+		}
+	}()
+
+	// select the common keys from the `default_values.yaml`
+	defaultValues, err := os.ReadFile(source.DefaultValuesPath())
+	if err != nil {
+		return exitcode.Wrap(exitcode.KindUnsupportedSource, err)
+	}
+	starter, missingKeys, err := values.NewStarter(defaultValues, append(append([]string{}, commonValuesKeys...), o.keys...))
+	if err != nil {
+		return exitcode.New(exitcode.KindUnsupportedSource, "failed to parse 'default_values.yaml' of the generator source: %v", err)
+	}
+	for _, keyPath := range missingKeys {
+		if contains(o.keys, keyPath) {
+			o.log.Warnf("the key '%s' does not exist in the generator source", keyPath)
+		} else {
+			o.log.Debugf("skipping common key which does not exist in the generator source: %s", keyPath)
+		}
+	}
+
+	if o.interactive {
+		err = o.prompt(starter, in, errOut)
+		if err != nil {
+			return err
+		}
+	}
+
+	data, err := starter.Marshal(o.header(source))
+	if err != nil {
+		return err
+	}
+	if o.output == "-" {
+		_, err = out.Write(data)
+		return err
+	}
+	// NOTE: the values file may contain secrets (like passwords), so it is only readable by the owner
+	err = os.WriteFile(o.output, data, 0600)
+	if err != nil {
+		return err
+	}
+	o.log.Infof("Created starter values file: %s", o.output)
+	return nil
+}
+
+// header returns the comment at the top of the starter values file
+func (o *valuesInitOptions) header(source *generator.Source) string {
+	sourceFlag := fmt.Sprintf("--source-version %s", source.Version())
+	if source.Version() == "" {
+		sourceFlag = fmt.Sprintf("--source-path %s", o.sourcePath)
+	}
+	return fmt.Sprintf(`deployKF starter values, created by 'deploykf values init %s'
+ - Only the most commonly changed keys are included, see the 'default_values.yaml' of the generator source for all keys.
+ - Generate the manifests with: deploykf generate %s --values <this file> --output-dir ./GENERATOR_OUTPUT`, sourceFlag, sourceFlag)
+}
+
+// prompt asks for the values of `valuesInitPrompts` (skipping keys which are not in the starter values)
+func (o *valuesInitOptions) prompt(starter *values.Starter, in io.Reader, errOut io.Writer) error {
+	reader := bufio.NewReader(in)
+	for _, p := range valuesInitPrompts {
+		defaultValue, ok := starter.Lookup(p.keyPath)
+		if !ok {
+			o.log.Debugf("skipping question for key which does not exist in the generator source: %s", p.keyPath)
+			continue
+		}
+		for {
+			fmt.Fprintf(errOut, "%s [%s]: ", p.question, defaultValue)
+			answer, err := reader.ReadString('\n')
+			if err != nil && !errors.Is(err, io.EOF) {
+				return err
+			}
+			answer = strings.TrimSpace(answer)
+			if errors.Is(err, io.EOF) && answer == "" {
+				// without more input, the remaining questions keep their defaults
+				fmt.Fprintln(errOut)
+				return nil
+			}
+			if answer == "" {
+				break
+			}
+			if p.boolean {
+				boolValue, valid := parseYesNo(answer)
+				if !valid {
+					fmt.Fprintln(errOut, "Please answer 'yes' or 'no'.")
+					continue
+				}
+				answer = boolValue
+			}
+			starter.SetScalar(p.keyPath, answer)
+			break
+		}
+	}
+	return nil
+}
+
+type valuesExplainOptions struct {
+	sourceVersion string
+	sourcePath    string
+
+	log *logging.Logger
+}
+
+func newValuesExplainCmd(out io.Writer, g *globalOptions) *cobra.Command {
+	o := &valuesExplainOptions{}
+
+	var cmd = &cobra.Command{
+		Use:   "explain <key>",
+		Short: "Describe a key of the default values, with its comments, type and default",
+		Long:  valuesExplainHelp,
+		Args:  require.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.log = g.log()
+			return o.run(cmd.Context(), out, args[0])
+		},
+	}
+
+	// add local flags
+	cmd.Flags().StringVarP(&o.sourceVersion, "source-version", "V", "", "a version tag from the 'deployKF/deployKF' GitHub repository")
+	cmd.Flags().StringVar(&o.sourcePath, "source-path", "", "a local path to a directory or '.zip' file containing a generator source")
+
+	// mark local flags
+	cmd.MarkFlagsMutuallyExclusive("source-version", "source-path")
+
+	return cmd
+}
+
+func (o *valuesExplainOptions) run(ctx context.Context, out io.Writer, keyPath string) error {
+	// verify the flags, before acquiring the source
+	if o.sourceVersion == "" && o.sourcePath == "" {
+		return exitcode.New(exitcode.KindInvalidFlags, "at least one of `--source-version` or `--source-path` must be provided")
+	}
+	keyPath = strings.Trim(keyPath, ".")
+	if keyPath == "" {
+		return exitcode.New(exitcode.KindInvalidFlags, "the provided key must not be empty")
+	}
+
+	source, err := generator.OpenSource(ctx, generator.SourceOptions{Version: o.sourceVersion, Path: o.sourcePath, Logger: o.log})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := source.Close()
+		if err != nil {
+			o.log.Warnf("%v", err)
+		}
+	}()
+
+	// read the schema of the key from the `values_schema.json` (if any)
+	var property schema.Property
+	var inSchema bool
+	valuesSchemaExists, err := generate.FileExists(source.ValuesSchemaPath())
+	if err != nil {
+		return err
+	}
+	if valuesSchemaExists {
+		valuesSchema, err := schema.Load(source.ValuesSchemaPath())
+		if err != nil {
+			return exitcode.New(exitcode.KindUnsupportedSource, "failed to read '%s' of the generator source: %v", generate.ValuesSchemaFile, err)
+		}
+		property, inSchema = valuesSchema.Property(keyPath)
+	}
+
+	defaultValues, err := os.ReadFile(source.DefaultValuesPath())
+	if err != nil {
+		return exitcode.Wrap(exitcode.KindUnsupportedSource, err)
+	}
+	explanation, err := values.Explain(defaultValues, keyPath)
+	if err != nil {
+		var missingErr *values.MissingKeyError
+		if !errors.As(err, &missingErr) {
+			return exitcode.New(exitcode.KindUnsupportedSource, "failed to parse 'default_values.yaml' of the generator source: %v", err)
+		}
+		if !inSchema {
+			return exitcode.Wrap(exitcode.KindInvalidFlags, err)
+		}
+		// keys which are only in the schema have no default value
+		explanation = &values.Explanation{KeyPath: keyPath}
+	}
+
+	printExplanation(out, explanation, property, inSchema)
+	return nil
+}
+
+// printExplanation prints the description of a values key, in the style of `kubectl explain`
+func printExplanation(out io.Writer, explanation *values.Explanation, property schema.Property, inSchema bool) {
+	const indent = "    "
+	typeName := explanation.Type
+	if typeName == "" {
+		typeName = property.Type
+	}
+	fmt.Fprintf(out, "KEY:   %s\n", explanation.KeyPath)
+	fmt.Fprintf(out, "TYPE:  %s\n", typeName)
+
+	var description []string
+	if property.Description != "" {
+		description = append(description, property.Description)
+	}
+	if explanation.Description != "" && explanation.Description != property.Description {
+		description = append(description, explanation.Description)
+	}
+	fmt.Fprintf(out, "\nDESCRIPTION:\n")
+	if len(description) == 0 {
+		fmt.Fprintf(out, "%s<no description>\n", indent)
+	}
+	for _, line := range strings.Split(strings.Join(description, "\n"), "\n") {
+		if line != "" {
+			fmt.Fprintf(out, "%s%s\n", indent, line)
+		}
+	}
+
+	if explanation.Type != "" && explanation.Type != "map" {
+		fmt.Fprintf(out, "\nDEFAULT:\n")
+		for _, line := range strings.Split(explanation.Default, "\n") {
+			fmt.Fprintf(out, "%s%s\n", indent, line)
+		}
+	}
+
+	if inSchema {
+		// NOTE: maps and lists are called "object" and "array" in JSON schemas
+		jsonTypeName := map[string]string{"map": "object", "list": "array"}[typeName]
+		if property.Type != "" && property.Type != typeName && property.Type != jsonTypeName {
+			fmt.Fprintf(out, "\nSCHEMA TYPE:\n%s%s\n", indent, property.Type)
+		}
+		if len(property.Enum) > 0 {
+			fmt.Fprintf(out, "\nALLOWED VALUES:\n")
+			for _, allowed := range property.Enum {
+				fmt.Fprintf(out, "%s%v\n", indent, allowed)
+			}
+		}
+		if len(property.Flags) > 0 {
+			fmt.Fprintf(out, "\nFLAGS:\n%s%s\n", indent, strings.Join(property.Flags, ", "))
+		}
+	}
+
+	if len(explanation.Children) > 0 {
+		nameWidth, typeWidth := 0, 0
+		for _, child := range explanation.Children {
+			if len(child.Name) > nameWidth {
+				nameWidth = len(child.Name)
+			}
+			if len(child.Type)+2 > typeWidth {
+				typeWidth = len(child.Type) + 2
+			}
+		}
+		fmt.Fprintf(out, "\nKEYS:\n")
+		for _, child := range explanation.Children {
+			line := fmt.Sprintf("%s%-*s  %-*s  %s", indent, nameWidth, child.Name, typeWidth, "<"+child.Type+">", child.Description)
+			fmt.Fprintln(out, strings.TrimRight(line, " "))
+		}
+	}
+}
+
+type valuesMigrateOptions struct {
+	from       string
+	to         string
+	sourcePath string
+	valuesFile string
+	output     string
+	force      bool
+
+	log *logging.Logger
+}
+
+func newValuesMigrateCmd(out io.Writer, g *globalOptions) *cobra.Command {
+	o := &valuesMigrateOptions{}
+
+	var cmd = &cobra.Command{
+		Use:   "migrate",
+		Short: "Update a values file from one version of deployKF to another",
+		Long:  valuesMigrateHelp,
+		Args:  require.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.log = g.log()
+			return o.run(cmd.Context(), out, cmd.InOrStdin())
+		},
+	}
+
+	// add local flags
+	cmd.Flags().StringVar(&o.from, "from", "", "the version of deployKF which the values file is for (like '0.1.3')")
+	cmd.Flags().StringVar(&o.to, "to", "", "the version of deployKF to update the values file for (like '0.1.4')")
+	cmd.Flags().StringVar(&o.sourcePath, "source-path", "", "a local path to a directory or '.zip' file containing the generator source of the '--to' version")
+	cmd.Flags().StringVarP(&o.valuesFile, "values", "f", "", "the YAML values file to update ('-' for stdin)")
+	cmd.Flags().StringVarP(&o.output, "output", "o", "-", "the file in which to write the updated values, or '-' for stdout")
+	cmd.Flags().BoolVar(&o.force, "force", false, "overwrite the '--output' file if it already exists")
+
+	return cmd
+}
+
+func (o *valuesMigrateOptions) run(ctx context.Context, out io.Writer, in io.Reader) error {
+	// verify the flags, before acquiring the source
+	if o.from == "" || o.to == "" {
+		return exitcode.New(exitcode.KindInvalidFlags, "both `--from` and `--to` must be provided")
+	}
+	if o.valuesFile == "" {
+		return exitcode.New(exitcode.KindInvalidFlags, "the `--values` file to update must be provided")
+	}
+	if o.output != "-" && !o.force {
+		outputIsDir, outputIsFile, err := generate.PathExists(o.output)
+		if err != nil {
+			return err
+		}
+		if outputIsDir || outputIsFile {
+			return exitcode.New(exitcode.KindInvalidFlags, "the provided --output '%s' already exists (use --force to overwrite it)", o.output)
+		}
+	}
+
+	// read the values file, before acquiring the source
+	var data []byte
+	var err error
+	if o.valuesFile == "-" {
+		data, err = io.ReadAll(in)
+	} else {
+		data, err = os.ReadFile(o.valuesFile)
+	}
+	if err != nil {
+		return exitcode.New(exitcode.KindInvalidValues, "failed to read values '%s': %v", o.valuesFile, err)
+	}
+	parsed, err := values.Parse(data)
+	if err != nil {
+		return exitcode.New(exitcode.KindInvalidValues, "failed to parse values '%s': %v", o.valuesFile, err)
+	}
+	if sops.IsEncrypted(parsed) {
+		return exitcode.New(exitcode.KindInvalidValues, "the values '%s' are encrypted with SOPS, decrypt them before migrating (like 'sops --decrypt --in-place %s')", o.valuesFile, o.valuesFile)
+	}
+
+	// NOTE: the rules are shipped in the generator source of the version we are migrating to
+	sourceOptions := generator.SourceOptions{Path: o.sourcePath, Logger: o.log}
+	if o.sourcePath == "" {
+		sourceOptions.Version = migrateSourceVersion(o.to)
+	}
+	source, err := generator.OpenSource(ctx, sourceOptions)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		err := source.Close()
+		if err != nil {
+			o.log.Warnf("%v", err)
+		}
+	}()
+
+	migrationsExist, err := generate.FileExists(source.ValuesMigrationsPath())
+	if err != nil {
+		return err
+	}
+	if !migrationsExist {
+		return exitcode.New(exitcode.KindUnsupportedSource, "the generator source has no '%s', so it does not support migrating values", values.MigrationsFile)
+	}
+	migrationsData, err := os.ReadFile(source.ValuesMigrationsPath())
+	if err != nil {
+		return exitcode.Wrap(exitcode.KindUnsupportedSource, err)
+	}
+	migrations, err := values.ParseMigrations(migrationsData)
+	if err != nil {
+		return exitcode.New(exitcode.KindUnsupportedSource, "failed to parse '%s' of the generator source: %v", values.MigrationsFile, err)
+	}
+	steps, err := migrations.Steps(o.from, o.to)
+	if err != nil {
+		return exitcode.Wrap(exitcode.KindInvalidFlags, err)
+	}
+
+	migrated, changes, err := values.Migrate(data, steps)
+	if err != nil {
+		return exitcode.New(exitcode.KindInvalidValues, "failed to migrate values '%s': %v", o.valuesFile, err)
+	}
+
+	// report the changes
+	manualCount := 0
+	for _, change := range changes {
+		if change.Manual {
+			manualCount++
+			o.log.Warnf("[%s] %s", change.Step, change.Message)
+		} else {
+			o.log.Infof("[%s] %s", change.Step, change.Message)
+		}
+	}
+
+	if o.output == "-" {
+		_, err = out.Write(migrated)
+	} else {
+		// NOTE: the values file may contain secrets (like passwords), so it is only readable by the owner
+		err = os.WriteFile(o.output, migrated, 0600)
+	}
+	if err != nil {
+		return err
+	}
+	if o.output != "-" {
+		o.log.Infof("Wrote updated values file: %s", o.output)
+	}
+
+	if manualCount > 0 {
+		o.log.Warnf("Migrated values from '%s' to '%s' with %d changes, but %d need manual attention", o.from, o.to, len(changes)-manualCount, manualCount)
+		return nil
+	}
+	o.log.Infof("Migrated values from '%s' to '%s' with %d changes", o.from, o.to, len(changes))
+	return nil
+}
+
+// parseYesNo parses a yes/no answer into "true" or "false"
+func parseYesNo(answer string) (string, bool) {
+	switch strings.ToLower(answer) {
+	case "y", "yes", "true":
+		return "true", true
+	case "n", "no", "false":
+		return "false", true
+	}
+	return "", false
+}
+
+// contains returns true if the list contains the value
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,20 @@
+package deploykf
+
+import (
+	"testing"
+
+	"github.com/deployKF/cli/internal/generate"
+)
+
+func TestMigrateSourceVersion(t *testing.T) {
+	sourceHelper := generate.NewSourceHelper()
+	for _, to := range []string{"0.1.4", "v0.1.4", " v0.1.4 "} {
+		version := migrateSourceVersion(to)
+		if tag := sourceHelper.ReleaseTag(version); tag != "v0.1.4" {
+			t.Errorf("release tag for --to %q = %q, want %q", to, tag, "v0.1.4")
+		}
+		if name := sourceHelper.ArtifactName(version); name != "deploykf-0.1.4-generator.zip" {
+			t.Errorf("artifact name for --to %q = %q, want %q", to, name, "deploykf-0.1.4-generator.zip")
+		}
+	}
+}
@@ -17,8 +17,12 @@ replace github.com/hairyhenderson/gomplate/v3 => github.com/deploykf/gomplate/v3
 
 require (
 	github.com/google/go-github/v50 v50.2.0
+	github.com/hairyhenderson/go-fsimpl v0.0.0-20220529183339-9deae3e35047
 	github.com/hairyhenderson/gomplate/v3 v3.11.5
+	github.com/spf13/afero v1.8.2
 	github.com/spf13/cobra v1.7.0
+	github.com/zealic/xignore v0.3.3
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -73,7 +77,6 @@ require (
 	github.com/googleapis/go-type-adapters v1.0.0 // indirect
 	github.com/gosimple/slug v1.12.0 // indirect
 	github.com/gosimple/unidecode v1.0.1 // indirect
-	github.com/hairyhenderson/go-fsimpl v0.0.0-20220529183339-9deae3e35047 // indirect
 	github.com/hairyhenderson/toml v0.4.2-0.20210923231440-40456b8e66cf // indirect
 	github.com/hairyhenderson/yaml v0.0.0-20220618171115-2d35fca545ce // indirect
 	github.com/hashicorp/consul/api v1.13.0 // indirect
@@ -116,11 +119,9 @@ require (
 	github.com/rs/zerolog v1.26.1 // indirect
 	github.com/ryanuber/go-glob v1.0.0 // indirect
 	github.com/sergi/go-diff v1.2.0 // indirect
-	github.com/spf13/afero v1.8.2 // indirect
 	github.com/spf13/pflag v1.0.5 // indirect
 	github.com/ugorji/go/codec v1.2.7 // indirect
 	github.com/xanzy/ssh-agent v0.3.1 // indirect
-	github.com/zealic/xignore v0.3.3 // indirect
 	go.etcd.io/bbolt v1.3.6 // indirect
 	go.opencensus.io v0.23.0 // indirect
 	go.uber.org/atomic v1.9.0 // indirect
@@ -0,0 +1,101 @@
+package exitcode
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kind is a category of error, which maps to a distinct process exit code.
+type Kind int
+
+// NOTE: these values are the documented exit codes of the CLI, so they must never be changed or reused
+const (
+	KindUnknown           Kind = 1  // an error without a more specific category
+	KindInvalidFlags      Kind = 2  // the command-line flags or arguments are invalid
+	KindSourceNotFound    Kind = 3  // the generator source does not exist
+	KindDownloadFailed    Kind = 4  // the generator source could not be downloaded
+	KindUnsupportedSource Kind = 5  // the generator source is invalid, or its schema version is not supported
+	KindInvalidValues     Kind = 6  // the values files could not be read or are invalid
+	KindRenderFailed      Kind = 7  // the templates could not be rendered
+	KindUnsafeOutputDir   Kind = 8  // the output directory is not safe to clean
+	KindCheckFailed       Kind = 9  // the generated manifests failed a check (invalid YAML, schema violations, or secret leaks)
+	KindInvalidConfig     Kind = 10 // the project config file could not be read or is invalid
+	KindCanceled          Kind = 11 // the command was interrupted (e.g. by Ctrl+C), or reached its timeout
+)
+
+// String returns the machine-readable name of the Kind, as used in JSON reports.
+func (k Kind) String() string {
+	switch k {
+	case KindUnknown:
+		return "error"
+	case KindInvalidFlags:
+		return "invalid_flags"
+	case KindSourceNotFound:
+		return "source_not_found"
+	case KindDownloadFailed:
+		return "download_failed"
+	case KindUnsupportedSource:
+		return "unsupported_source"
+	case KindInvalidValues:
+		return "invalid_values"
+	case KindRenderFailed:
+		return "render_failed"
+	case KindUnsafeOutputDir:
+		return "unsafe_output_dir"
+	case KindCheckFailed:
+		return "check_failed"
+	case KindInvalidConfig:
+		return "invalid_config"
+	case KindCanceled:
+		return "canceled"
+	}
+	return fmt.Sprintf("kind(%d)", int(k))
+}
+
+// Error is an error with a Kind.
+type Error struct {
+	Kind Kind
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New creates an Error of the Kind with a formatted message.
+func New(kind Kind, format string, args ...interface{}) error {
+	return &Error{Kind: kind, Err: fmt.Errorf(format, args...)}
+}
+
+// Wrap gives an error the Kind, unless it is nil or already has a Kind.
+func Wrap(kind Kind, err error) error {
+	if err == nil {
+		return nil
+	}
+	var kindErr *Error
+	if errors.As(err, &kindErr) {
+		return err
+	}
+	return &Error{Kind: kind, Err: err}
+}
+
+// KindOf returns the Kind of an error, or KindUnknown if it has none.
+func KindOf(err error) Kind {
+	var kindErr *Error
+	if errors.As(err, &kindErr) {
+		return kindErr.Kind
+	}
+	return KindUnknown
+}
+
+// Code returns the process exit code for an error, which is 0 for a nil error.
+func Code(err error) int {
+	if err == nil {
+		return 0
+	}
+	return int(KindOf(err))
+}
@@ -0,0 +1,192 @@
+package generate
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// archiveModTime is the fixed modification time used for all archive entries, so that archives are reproducible.
+// NOTE: the zip format can't represent times before 1980
+var archiveModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// IsSupportedArchive returns true if the file extension of the path is a supported archive format.
+func IsSupportedArchive(archivePath string) bool {
+	return strings.HasSuffix(archivePath, ".tar.gz") ||
+		strings.HasSuffix(archivePath, ".tgz") ||
+		strings.HasSuffix(archivePath, ".zip")
+}
+
+// TrimArchiveExtension returns the archive path without its archive file extension.
+func TrimArchiveExtension(archivePath string) string {
+	for _, ext := range []string{".tar.gz", ".tgz", ".zip"} {
+		if strings.HasSuffix(archivePath, ext) {
+			return strings.TrimSuffix(archivePath, ext)
+		}
+	}
+	return archivePath
+}
+
+// WriteArchive packages the contents of the source directory into a `.tar.gz`, `.tgz` or `.zip` archive.
+// The archive is deterministic: entries are sorted, and all timestamps, owners and modes are fixed,
+// so identical directory contents always produce byte-identical archives.
+func WriteArchive(srcDir string, archivePath string) error {
+	if !IsSupportedArchive(archivePath) {
+		return fmt.Errorf("unsupported archive format '%s': must be '.tar.gz', '.tgz' or '.zip'", archivePath)
+	}
+
+	// list the entries in a stable order
+	// NOTE: `filepath.Walk` visits entries in lexical order
+	var relPaths []string
+	err := filepath.Walk(srcDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+		if relPath != "." {
+			relPaths = append(relPaths, relPath)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// create the parent directory of the archive, if needed
+	err = os.MkdirAll(filepath.Dir(archivePath), 0755)
+	if err != nil {
+		return err
+	}
+
+	// write to a temporary file first, so a failed run never leaves a partial archive behind
+	tempFile, err := os.CreateTemp(filepath.Dir(archivePath), ".deploykf-archive-*")
+	if err != nil {
+		return err
+	}
+	tempPath := tempFile.Name()
+	defer os.Remove(tempPath)
+
+	if strings.HasSuffix(archivePath, ".zip") {
+		err = writeZipArchive(tempFile, srcDir, relPaths)
+	} else {
+		err = writeTarGzArchive(tempFile, srcDir, relPaths)
+	}
+	if err != nil {
+		tempFile.Close()
+		return err
+	}
+	err = tempFile.Close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(tempPath, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tempPath, archivePath)
+}
+
+// writeTarGzArchive writes the listed paths into a gzip-compressed tar archive.
+func writeTarGzArchive(w io.Writer, srcDir string, relPaths []string) error {
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	for _, relPath := range relPaths {
+		path := filepath.Join(srcDir, relPath)
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+
+		header := &tar.Header{
+			Name:    filepath.ToSlash(relPath),
+			ModTime: archiveModTime,
+		}
+		if info.IsDir() {
+			header.Typeflag = tar.TypeDir
+			header.Name += "/"
+			header.Mode = 0755
+		} else {
+			header.Typeflag = tar.TypeReg
+			header.Mode = 0644
+			header.Size = info.Size()
+		}
+
+		err = tarWriter.WriteHeader(header)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			err = copyFileTo(tarWriter, path)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err := tarWriter.Close()
+	if err != nil {
+		return err
+	}
+	return gzipWriter.Close()
+}
+
+// writeZipArchive writes the listed paths into a zip archive.
+func writeZipArchive(w io.Writer, srcDir string, relPaths []string) error {
+	zipWriter := zip.NewWriter(w)
+
+	for _, relPath := range relPaths {
+		path := filepath.Join(srcDir, relPath)
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+
+		header := &zip.FileHeader{
+			Name:     filepath.ToSlash(relPath),
+			Modified: archiveModTime,
+		}
+		if info.IsDir() {
+			header.Name += "/"
+			header.SetMode(os.ModeDir | 0755)
+		} else {
+			header.Method = zip.Deflate
+			header.SetMode(0644)
+		}
+
+		entryWriter, err := zipWriter.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			err = copyFileTo(entryWriter, path)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return zipWriter.Close()
+}
+
+// copyFileTo copies the contents of a file into the writer.
+func copyFileTo(w io.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = io.Copy(w, file)
+	return err
+}
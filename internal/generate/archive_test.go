@@ -0,0 +1,191 @@
+package generate
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// archiveTestFiles are the files of the tree which is archived by the tests
+var archiveTestFiles = map[string]string{
+	".deploykf_output":                    `{"cli_version":"test"}`,
+	"app-of-apps.yaml":                    "kind: Application\n",
+	"manifests/argocd/kustomization.yaml": "resources: []\n",
+	"manifests/kubeflow/pipelines/a.yaml": "kind: ConfigMap\n",
+	"manifests/kubeflow/pipelines/b.yaml": "kind: Secret\n",
+	"manifests/empty.yaml":                "",
+}
+
+// writeArchiveTestTree writes the files of archiveTestFiles with a file mode and modification time,
+// in a different order each time (so the order of creation doesn't matter)
+func writeArchiveTestTree(t *testing.T, mode os.FileMode, modTime time.Time, reverse bool) string {
+	t.Helper()
+	dir := t.TempDir()
+	relPaths := make([]string, 0, len(archiveTestFiles))
+	for relPath := range archiveTestFiles {
+		relPaths = append(relPaths, relPath)
+	}
+	if reverse {
+		for i, j := 0, len(relPaths)-1; i < j; i, j = i+1, j-1 {
+			relPaths[i], relPaths[j] = relPaths[j], relPaths[i]
+		}
+	}
+	for _, relPath := range relPaths {
+		path := filepath.Join(dir, filepath.FromSlash(relPath))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(archiveTestFiles[relPath]), mode); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chmod(path, mode); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestWriteArchiveIsReproducible(t *testing.T) {
+	for _, extension := range []string{".tar.gz", ".tgz", ".zip"} {
+		t.Run(extension, func(t *testing.T) {
+			// the trees only differ in their file modes, modification times and order of creation
+			dir1 := writeArchiveTestTree(t, 0644, time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), false)
+			dir2 := writeArchiveTestTree(t, 0600, time.Date(2023, 6, 15, 12, 30, 0, 0, time.UTC), true)
+
+			outDir := t.TempDir()
+			archive1 := filepath.Join(outDir, "one"+extension)
+			archive2 := filepath.Join(outDir, "two"+extension)
+			if err := WriteArchive(dir1, archive1); err != nil {
+				t.Fatal(err)
+			}
+			// NOTE: the second archive is written later, so the current time also differs
+			time.Sleep(1100 * time.Millisecond)
+			if err := WriteArchive(dir2, archive2); err != nil {
+				t.Fatal(err)
+			}
+
+			data1, err := os.ReadFile(archive1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data2, err := os.ReadFile(archive2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data1, data2) {
+				t.Errorf("archives of identical trees differ (%d and %d bytes)", len(data1), len(data2))
+			}
+
+			// the archive contains every file, with its contents
+			files := readTestArchive(t, archive1)
+			if !reflect.DeepEqual(files, archiveTestFiles) {
+				t.Errorf("archive files = %q, want %q", files, archiveTestFiles)
+			}
+		})
+	}
+}
+
+func TestWriteArchiveChangesWithContent(t *testing.T) {
+	for _, extension := range []string{".tar.gz", ".zip"} {
+		t.Run(extension, func(t *testing.T) {
+			modTime := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+			dir1 := writeArchiveTestTree(t, 0644, modTime, false)
+			dir2 := writeArchiveTestTree(t, 0644, modTime, false)
+			if err := os.WriteFile(filepath.Join(dir2, "app-of-apps.yaml"), []byte("kind: Other\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			outDir := t.TempDir()
+			archive1 := filepath.Join(outDir, "one"+extension)
+			archive2 := filepath.Join(outDir, "two"+extension)
+			if err := WriteArchive(dir1, archive1); err != nil {
+				t.Fatal(err)
+			}
+			if err := WriteArchive(dir2, archive2); err != nil {
+				t.Fatal(err)
+			}
+			data1, _ := os.ReadFile(archive1)
+			data2, _ := os.ReadFile(archive2)
+			if bytes.Equal(data1, data2) {
+				t.Errorf("archives of different trees are identical")
+			}
+		})
+	}
+}
+
+// readTestArchive returns the contents of the regular files in an archive, by their path
+func readTestArchive(t *testing.T, archivePath string) map[string]string {
+	t.Helper()
+	files := map[string]string{}
+	if filepath.Ext(archivePath) == ".zip" {
+		reader, err := zip.OpenReader(archivePath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer reader.Close()
+		for _, file := range reader.File {
+			if file.FileInfo().IsDir() {
+				continue
+			}
+			if !file.Modified.Equal(archiveModTime) {
+				t.Errorf("zip entry '%s' has modification time %v, want %v", file.Name, file.Modified, archiveModTime)
+			}
+			entry, err := file.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := io.ReadAll(entry)
+			entry.Close()
+			if err != nil {
+				t.Fatal(err)
+			}
+			files[file.Name] = string(data)
+		}
+		return files
+	}
+
+	archiveFile, err := os.Open(archivePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer archiveFile.Close()
+	gzipReader, err := gzip.NewReader(archiveFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !header.ModTime.Equal(archiveModTime) {
+			t.Errorf("tar entry '%s' has modification time %v, want %v", header.Name, header.ModTime, archiveModTime)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if header.Mode != 0644 {
+			t.Errorf("tar entry '%s' has mode %o, want 644", header.Name, header.Mode)
+		}
+		data, err := io.ReadAll(tarReader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[header.Name] = string(data)
+	}
+	return files
+}
@@ -2,6 +2,7 @@ package generate
 
 import (
 	"archive/zip"
+	"context"
 	"fmt"
 	"io"
 	"os"
@@ -42,10 +43,44 @@ func FileExists(file string) (bool, error) {
 	return !info.IsDir(), nil
 }
 
+// ListFiles returns the slash-separated paths (relative to the directory) of all files under the directory.
+// If the directory does not exist, an empty list is returned.
+func ListFiles(dir string) ([]string, error) {
+	dirExists, err := DirectoryExists(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !dirExists {
+		return nil, nil
+	}
+
+	var files []string
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(relPath))
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
 // UnzipFile extracts the contents of a .zip file to a destination directory
 // extractPath is the relative path inside the zip archive that should be extracted
 // If extractPath does not match any files or directories in the zip archive, an error is returned
-func UnzipFile(zipFilePath string, targetDir string, extractPath string) error {
+// If the context is cancelled, extraction stops before the next file
+func UnzipFile(ctx context.Context, zipFilePath string, targetDir string, extractPath string) error {
 	// Open the zip file
 	reader, err := zip.OpenReader(zipFilePath)
 	if err != nil {
@@ -62,6 +97,10 @@ func UnzipFile(zipFilePath string, targetDir string, extractPath string) error {
 
 	// Iterate through each file in the zip archive
 	for _, file := range reader.File {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		// Normalize file.Name before processing
 		// NOTE: zip files always use forward slashes, so we need to convert the OS-specific path separator
 		normalizedFileName := filepath.Clean(filepath.FromSlash(file.Name))
@@ -130,11 +169,15 @@ func UnzipFile(zipFilePath string, targetDir string, extractPath string) error {
 }
 
 // CopyFolder recursively copies the contents of the source folder to the destination folder
-func CopyFolder(src, dest string) error {
+// If the context is cancelled, copying stops before the next file
+func CopyFolder(ctx context.Context, src, dest string) error {
 	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 
 		// Get the relative path of the current file/directory within the source folder
 		relPath, err := filepath.Rel(src, path)
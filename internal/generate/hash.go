@@ -1,6 +1,7 @@
 package generate
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/hex"
 	"os"
@@ -13,7 +14,8 @@ import (
 // If the path is a file, it computes the hash of the file.
 // If the path is a folder, it computes the hash of all files within the folder.
 // It accepts an additional argument 'ignoreNames' which is a slice of file names to ignore.
-func HashPath(path string, ignoreNames []string) (string, error) {
+// If the context is cancelled, hashing stops before the next file.
+func HashPath(ctx context.Context, path string, ignoreNames []string) (string, error) {
 	info, err := os.Stat(path)
 	if err != nil {
 		return "", err
@@ -23,7 +25,7 @@ func HashPath(path string, ignoreNames []string) (string, error) {
 		return hashFile(path)
 	}
 
-	return hashDirectory(path, ignoreNames)
+	return hashDirectory(ctx, path, ignoreNames)
 }
 
 // hashFile takes a file path as input and returns the SHA-256 hash of the file.
@@ -41,7 +43,7 @@ func hashFile(filePath string) (string, error) {
 // of all files within the directory, excluding files with the specified names in the ignoreNames slice.
 // It ensures a consistent hash across different operating systems (Windows, Linux, and macOS)
 // by normalizing file paths, using case-insensitive sorting, and using relative paths when computing the hash.
-func hashDirectory(dirPath string, ignoreNames []string) (string, error) {
+func hashDirectory(ctx context.Context, dirPath string, ignoreNames []string) (string, error) {
 	var files []string
 
 	err := filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
@@ -70,6 +72,9 @@ func hashDirectory(dirPath string, ignoreNames []string) (string, error) {
 
 	hasher := sha256.New()
 	for _, relPath := range files {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
 		absPath := filepath.Join(dirPath, filepath.FromSlash(relPath))
 		fileHash, err := hashFile(absPath)
 		if err != nil {
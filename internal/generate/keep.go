@@ -0,0 +1,170 @@
+package generate
+
+import (
+	"bufio"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/zealic/xignore"
+)
+
+const (
+	// DeployKFKeepFile is the name of an optional file in output directories which contains gitignore-style patterns,
+	// paths matching these patterns are not removed when cleaning, and are not overwritten by the generator.
+	DeployKFKeepFile = ".deploykf_keep"
+)
+
+// keepPattern is a single gitignore-style pattern.
+type keepPattern struct {
+	pattern  *xignore.Pattern
+	dirsOnly bool
+}
+
+// KeepMatcher decides which paths in an output directory should be preserved.
+type KeepMatcher struct {
+	patterns []keepPattern
+}
+
+// NewKeepMatcher creates a KeepMatcher from the `.deploykf_keep` file in the output directory (if it exists),
+// followed by any extra patterns (which take precedence, as later patterns override earlier ones).
+func NewKeepMatcher(outputDir string, extraPatterns []string) (*KeepMatcher, error) {
+	var lines []string
+
+	// read the patterns from the keep file, if it exists
+	keepFilePath := filepath.Join(outputDir, DeployKFKeepFile)
+	keepFileExists, err := FileExists(keepFilePath)
+	if err != nil {
+		return nil, err
+	}
+	if keepFileExists {
+		file, err := os.Open(keepFilePath)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	// the keep file itself is always kept
+	lines = append(lines, "/"+DeployKFKeepFile)
+	lines = append(lines, extraPatterns...)
+
+	return newPatternMatcher(lines), nil
+}
+
+// newPatternMatcher creates a KeepMatcher from a list of gitignore-style patterns.
+func newPatternMatcher(lines []string) *KeepMatcher {
+	m := &KeepMatcher{}
+	for _, line := range lines {
+		pattern, ok := compileKeepPattern(line)
+		if ok {
+			m.patterns = append(m.patterns, pattern)
+		}
+	}
+	return m
+}
+
+// Match returns true if the provided slash-separated path (relative to the output directory) should be kept.
+// A path is also kept if any of its parent directories are kept.
+func (m *KeepMatcher) Match(relPath string, isDir bool) bool {
+	if m == nil || len(m.patterns) == 0 {
+		return false
+	}
+
+	relPath = strings.Trim(path.Clean(filepath.ToSlash(relPath)), "/")
+	parts := strings.Split(relPath, "/")
+	for i := range parts {
+		partIsDir := isDir || i < len(parts)-1
+		if m.matchSingle(strings.Join(parts[:i+1], "/"), partIsDir) {
+			return true
+		}
+	}
+	return false
+}
+
+// HasPatterns returns true if any user-provided patterns were loaded.
+func (m *KeepMatcher) HasPatterns() bool {
+	// NOTE: the first implicit pattern is the keep file itself
+	return m != nil && len(m.patterns) > 1
+}
+
+// matchSingle checks a single path against all patterns, where the last matching pattern wins.
+func (m *KeepMatcher) matchSingle(relPath string, isDir bool) bool {
+	matched := false
+	for _, p := range m.patterns {
+		if p.dirsOnly && !isDir {
+			continue
+		}
+		if p.pattern.Match(filepath.FromSlash(relPath)) {
+			matched = !p.pattern.IsExclusion()
+		}
+	}
+	return matched
+}
+
+// compileKeepPattern converts a gitignore-style pattern into a keepPattern (matched with the same library as
+// the `.gomplateignore` files), returns false if the line is empty or a comment.
+//   - note, the library doesn't support trailing slashes, "[!...]" classes, or literal regexp characters
+//     (like "+"), so they are handled before the pattern is compiled
+func compileKeepPattern(line string) (keepPattern, bool) {
+	p := keepPattern{}
+
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return p, false
+	}
+	negate := strings.HasPrefix(line, "!")
+	line = strings.TrimPrefix(line, "!")
+	if strings.HasSuffix(line, "/") && !strings.HasSuffix(line, "\\/") {
+		p.dirsOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return p, false
+	}
+
+	var sb strings.Builder
+	if negate {
+		sb.WriteString("!")
+	}
+	for i := 0; i < len(line); i++ {
+		ch := line[i]
+		switch {
+		case ch == '\\' && i+1 < len(line):
+			i++
+			sb.WriteString("\\" + string(line[i]))
+		case ch == '[':
+			end := strings.IndexByte(line[i+1:], ']')
+			if end < 0 {
+				// an unclosed class is a literal '['
+				sb.WriteString("\\[")
+				continue
+			}
+			class := line[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+			i += end + 1
+		case strings.IndexByte("+(){}|^]", ch) != -1:
+			sb.WriteString("\\" + string(ch))
+		default:
+			sb.WriteByte(ch)
+		}
+	}
+
+	p.pattern = xignore.NewPattern(sb.String())
+	if p.pattern.IsEmpty() || p.pattern.Prepare() != nil {
+		return p, false
+	}
+	return p, true
+}
@@ -0,0 +1,127 @@
+package generate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestKeepMatcherMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		isDir    bool
+		want     bool
+	}{
+		// plain names match at any depth
+		{"name at root", []string{"README.md"}, "README.md", false, true},
+		{"name nested", []string{"README.md"}, "docs/README.md", false, true},
+		{"name prefix is not a match", []string{"README.md"}, "README.md.bak", false, false},
+		{"name keeps children of a dir", []string{"secrets"}, "app/secrets/token.yaml", false, true},
+
+		// wildcards
+		{"star within a name", []string{"*.md"}, "docs/notes.md", false, true},
+		{"star does not cross slashes", []string{"docs/*.md"}, "docs/a/notes.md", false, false},
+		{"question mark", []string{"file?.txt"}, "file1.txt", false, true},
+		{"question mark needs one char", []string{"file?.txt"}, "file.txt", false, false},
+
+		// double star
+		{"leading double star", []string{"**/keep.yaml"}, "a/b/keep.yaml", false, true},
+		{"leading double star at root", []string{"**/keep.yaml"}, "keep.yaml", false, true},
+		{"trailing double star", []string{"manual/**"}, "manual/a/b.yaml", false, true},
+		{"trailing double star is anchored", []string{"manual/**"}, "other/manual/a.yaml", false, false},
+		{"middle double star", []string{"a/**/z.yaml"}, "a/b/c/z.yaml", false, true},
+		{"middle double star matches zero dirs", []string{"a/**/z.yaml"}, "a/z.yaml", false, true},
+
+		// anchoring
+		{"leading slash at root", []string{"/keep.yaml"}, "keep.yaml", false, true},
+		{"leading slash is anchored", []string{"/keep.yaml"}, "sub/keep.yaml", false, false},
+		{"middle slash is anchored", []string{"a/keep.yaml"}, "x/a/keep.yaml", false, false},
+
+		// trailing slash only matches directories
+		{"trailing slash matches dir", []string{"manual/"}, "manual", true, true},
+		{"trailing slash skips file", []string{"manual/"}, "manual", false, false},
+		{"trailing slash keeps children", []string{"manual/"}, "manual/a.yaml", false, true},
+		{"trailing slash nested dir", []string{"manual/"}, "x/manual/a.yaml", false, true},
+
+		// negation, where the last matching pattern wins
+		{"negation", []string{"*.yaml", "!b.yaml"}, "b.yaml", false, false},
+		{"negation other file", []string{"*.yaml", "!b.yaml"}, "a.yaml", false, true},
+		{"negation then re-include", []string{"*.yaml", "!b.yaml", "b.yaml"}, "b.yaml", false, true},
+		{"negation of child of kept dir", []string{"manual/", "!manual/a.yaml"}, "manual/a.yaml", false, true},
+
+		// escaped and literal characters
+		{"escaped star", []string{`\*.yaml`}, "*.yaml", false, true},
+		{"escaped star is literal", []string{`\*.yaml`}, "a.yaml", false, false},
+		{"escaped bang", []string{`\!important`}, "!important", false, true},
+		{"escaped hash", []string{`\#notes`}, "#notes", false, true},
+		{"comment is ignored", []string{"#notes"}, "#notes", false, false},
+		{"literal plus", []string{"a+b.yaml"}, "a+b.yaml", false, true},
+		{"literal plus is not a repeat", []string{"a+b.yaml"}, "aab.yaml", false, false},
+		{"literal parens", []string{"(copy).yaml"}, "(copy).yaml", false, true},
+		{"literal dot", []string{"a.yaml"}, "axyaml", false, false},
+		{"trailing spaces are ignored", []string{"a.yaml  "}, "a.yaml", false, true},
+
+		// character classes
+		{"class", []string{"file[12].txt"}, "file2.txt", false, true},
+		{"class no match", []string{"file[12].txt"}, "file3.txt", false, false},
+		{"class range", []string{"file[a-c].txt"}, "fileb.txt", false, true},
+		{"negated class", []string{"file[!12].txt"}, "file3.txt", false, true},
+		{"negated class no match", []string{"file[!12].txt"}, "file1.txt", false, false},
+		{"unclosed class is literal", []string{"file[1.txt"}, "file[1.txt", false, true},
+
+		// no patterns
+		{"no patterns", []string{}, "a.yaml", false, false},
+		{"only comments", []string{"# comment", ""}, "a.yaml", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newPatternMatcher(tt.patterns).Match(tt.path, tt.isDir)
+			if got != tt.want {
+				t.Errorf("Match(%q, %v) with patterns %q = %v, want %v", tt.path, tt.isDir, tt.patterns, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewKeepMatcher(t *testing.T) {
+	outputDir := t.TempDir()
+	err := os.WriteFile(filepath.Join(outputDir, DeployKFKeepFile), []byte("# my files\nmanual/\n!manual/generated.yaml\n"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keep, err := NewKeepMatcher(outputDir, []string{"extra.yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !keep.HasPatterns() {
+		t.Errorf("HasPatterns() = false, want true")
+	}
+	for path, want := range map[string]bool{
+		DeployKFKeepFile:          true,
+		"sub/" + DeployKFKeepFile: false,
+		"manual/notes.md":         true,
+		"manual/generated.yaml":   true, // the parent directory is kept
+		"extra.yaml":              true,
+		"argocd/app.yaml":         false,
+	} {
+		if got := keep.Match(path, false); got != want {
+			t.Errorf("Match(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestNewKeepMatcherWithoutFile(t *testing.T) {
+	keep, err := NewKeepMatcher(t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keep.HasPatterns() {
+		t.Errorf("HasPatterns() = true, want false")
+	}
+	if !keep.Match(DeployKFKeepFile, false) {
+		t.Errorf("the keep file itself must always be kept")
+	}
+}
@@ -0,0 +1,198 @@
+package generate
+
+import (
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/deployKF/cli/internal/schema"
+)
+
+// KubeObjectError describes a schema violation in a generated Kubernetes object.
+type KubeObjectError struct {
+	File       string `json:"file"`
+	APIVersion string `json:"api_version,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+	Name       string `json:"name,omitempty"`
+	Namespace  string `json:"namespace,omitempty"`
+	Field      string `json:"field,omitempty"`
+	Message    string `json:"message"`
+}
+
+// KubeObjectRef identifies a generated Kubernetes object.
+type KubeObjectRef struct {
+	File       string `json:"file"`
+	APIVersion string `json:"api_version"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name,omitempty"`
+	Namespace  string `json:"namespace,omitempty"`
+}
+
+// KubeValidationResult is the result of validating generated Kubernetes objects against their schemas.
+type KubeValidationResult struct {
+	ValidObjects   int               `json:"valid_objects"`
+	InvalidObjects int               `json:"invalid_objects"`
+	SkippedObjects int               `json:"skipped_objects"`
+	Errors         []KubeObjectError `json:"errors"`
+	MissingSchemas []KubeObjectRef   `json:"missing_schemas"`
+}
+
+// KubeSchemaLoader finds and caches the JSON schemas for Kubernetes objects.
+//
+// Schemas are read from local directories using the same layouts as kubeconform:
+//   - built-in types: `{location}/{kube version}-standalone-strict/{kind}-{group}-{version}.json`
+//   - custom resources: `{location}/{group}/{kind}_{version}.json`
+type KubeSchemaLoader struct {
+	kubernetesVersion string
+	locations         []string
+	cache             map[string]*schema.Schema
+}
+
+// NewKubeSchemaLoader creates a KubeSchemaLoader which searches the provided locations (in order).
+// The kubernetesVersion may be a semantic version like "1.26.0" or "master".
+func NewKubeSchemaLoader(kubernetesVersion string, locations []string) *KubeSchemaLoader {
+	if kubernetesVersion != "master" && !strings.HasPrefix(kubernetesVersion, "v") {
+		kubernetesVersion = "v" + kubernetesVersion
+	}
+	return &KubeSchemaLoader{
+		kubernetesVersion: kubernetesVersion,
+		locations:         locations,
+		cache:             map[string]*schema.Schema{},
+	}
+}
+
+// Get returns the schema for the provided apiVersion and kind, or nil if no schema was found.
+func (l *KubeSchemaLoader) Get(apiVersion string, kind string) (*schema.Schema, error) {
+	cacheKey := apiVersion + "/" + kind
+	if s, ok := l.cache[cacheKey]; ok {
+		return s, nil
+	}
+
+	group, version := "", apiVersion
+	if i := strings.LastIndex(apiVersion, "/"); i >= 0 {
+		group, version = apiVersion[:i], apiVersion[i+1:]
+	}
+	lowerKind := strings.ToLower(kind)
+
+	// built-in types use the first part of the group in their file name
+	builtinName := lowerKind + "-" + strings.ToLower(version) + ".json"
+	if group != "" {
+		builtinName = lowerKind + "-" + strings.ToLower(strings.Split(group, ".")[0]) + "-" + strings.ToLower(version) + ".json"
+	}
+
+	var candidates []string
+	for _, location := range l.locations {
+		candidates = append(candidates,
+			filepath.Join(location, l.kubernetesVersion+"-standalone-strict", builtinName),
+			filepath.Join(location, l.kubernetesVersion+"-standalone", builtinName),
+		)
+		if group != "" {
+			candidates = append(candidates, filepath.Join(location, group, lowerKind+"_"+strings.ToLower(version)+".json"))
+		}
+	}
+
+	var found *schema.Schema
+	for _, candidate := range candidates {
+		exists, err := FileExists(candidate)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			found, err = schema.Load(candidate)
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+
+	l.cache[cacheKey] = found
+	return found, nil
+}
+
+// ValidateKubernetesObjects validates every Kubernetes object in the YAML files under the directory against its schema.
+// Objects without a schema are counted as skipped, and files which are not valid YAML are ignored.
+func ValidateKubernetesObjects(dir string, loader *KubeSchemaLoader) (*KubeValidationResult, error) {
+	result := &KubeValidationResult{
+		Errors:         []KubeObjectError{},
+		MissingSchemas: []KubeObjectRef{},
+	}
+	err := forEachYAMLDocument(dir, func(relPath string, document *yaml.Node, err error) error {
+		// NOTE: syntax errors are reported by `ValidateYAMLFiles`
+		if err != nil {
+			return nil
+		}
+		var obj interface{}
+		if err := document.Decode(&obj); err != nil {
+			return nil
+		}
+		if obj == nil {
+			return nil
+		}
+		return validateKubernetesObject(relPath, obj, loader, result)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// validateKubernetesObject validates a single decoded object, and records the outcome in the result.
+func validateKubernetesObject(relPath string, obj interface{}, loader *KubeSchemaLoader, result *KubeValidationResult) error {
+	objMap, ok := obj.(map[string]interface{})
+	if !ok {
+		result.InvalidObjects++
+		result.Errors = append(result.Errors, KubeObjectError{File: relPath, Message: "document is not a Kubernetes object"})
+		return nil
+	}
+
+	ref := KubeObjectRef{File: relPath}
+	ref.APIVersion, _ = objMap["apiVersion"].(string)
+	ref.Kind, _ = objMap["kind"].(string)
+	if metadata, ok := objMap["metadata"].(map[string]interface{}); ok {
+		ref.Name, _ = metadata["name"].(string)
+		ref.Namespace, _ = metadata["namespace"].(string)
+	}
+
+	if ref.APIVersion == "" || ref.Kind == "" {
+		result.InvalidObjects++
+		result.Errors = append(result.Errors, KubeObjectError{
+			File:    relPath,
+			Name:    ref.Name,
+			Message: "object is missing 'apiVersion' or 'kind'",
+		})
+		return nil
+	}
+
+	s, err := loader.Get(ref.APIVersion, ref.Kind)
+	if err != nil {
+		return err
+	}
+	if s == nil {
+		result.SkippedObjects++
+		result.MissingSchemas = append(result.MissingSchemas, ref)
+		return nil
+	}
+
+	violations := s.Validate(objMap)
+	if len(violations) == 0 {
+		result.ValidObjects++
+		return nil
+	}
+
+	result.InvalidObjects++
+	for _, violation := range violations {
+		result.Errors = append(result.Errors, KubeObjectError{
+			File:       ref.File,
+			APIVersion: ref.APIVersion,
+			Kind:       ref.Kind,
+			Name:       ref.Name,
+			Namespace:  ref.Namespace,
+			Field:      violation.Field,
+			Message:    violation.Message,
+		})
+	}
+	return nil
+}
@@ -2,10 +2,14 @@ package generate
 
 import (
 	"encoding/json"
-	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
+	"strings"
 	"time"
+
+	"github.com/deployKF/cli/internal/exitcode"
 )
 
 const (
@@ -15,70 +19,268 @@ const (
 )
 
 type RunInfo struct {
-	GeneratedAt   string `json:"generated_at"`
+	GeneratedAt   string `json:"generated_at,omitempty"`
 	SourceVersion string `json:"source_version,omitempty"`
 	SourcePath    string `json:"source_path,omitempty"`
 	SourceHash    string `json:"source_hash,omitempty"`
 	CLIVersion    string `json:"cli_version"`
+
+	// OutputFiles lists the slash-separated paths (relative to the output directory) of every generated file,
+	// when present, only these files are removed when the output directory is next cleaned.
+	OutputFiles []string `json:"output_files,omitempty"`
+
+	// Partial is true if only some templates were rendered (because of `--only` or `--skip` patterns),
+	// in which case the patterns are also recorded.
+	Partial bool     `json:"partial,omitempty"`
+	Only    []string `json:"only,omitempty"`
+	Skip    []string `json:"skip,omitempty"`
 }
 
-// CleanOutputDirectory cleans the output directory if it's safe to do so.
-func CleanOutputDirectory(outputDir string) error {
+// CleanOutputDirectory cleans the output directory if it's safe to do so, and returns the number of files removed.
+// Paths matched by the KeepMatcher (and their parent directories) are not removed.
+// If the marker file lists the files from the previous run, only those files are removed.
+func CleanOutputDirectory(outputDir string, keep *KeepMatcher, allowUnsafe bool) (int, error) {
 	// Check if the output directory exists, and return if not.
 	dirExists, err := DirectoryExists(outputDir)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	if !dirExists {
-		return nil
+		return 0, nil
 	}
 
 	// Check if the output folder is empty, and return if so.
 	files, err := os.ReadDir(outputDir)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	if len(files) == 0 {
-		return nil
+		return 0, nil
 	}
 
 	// Output directory is non-empty, check if it contains a marker file, and fail if not.
 	markerFile := filepath.Join(outputDir, DeployKFOutputMarker)
 	markerFileExists, err := FileExists(markerFile)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	if !markerFileExists {
-		return fmt.Errorf("output directory '%s' is not safe to clean: no '%s' marker found", outputDir, DeployKFOutputMarker)
+		return 0, exitcode.New(exitcode.KindUnsafeOutputDir, "output directory '%s' is not safe to clean: no '%s' marker found", outputDir, DeployKFOutputMarker)
+	}
+
+	// Refuse to clean dangerous targets, even if they contain a marker file.
+	err = CheckOutputDirectorySafety(outputDir, keep, allowUnsafe)
+	if err != nil {
+		return 0, err
+	}
+
+	// If the previous run recorded its output files, only remove those files.
+	// NOTE: a partial run always records its output files, even if there were none
+	runInfo, err := ReadMarkerFile(outputDir)
+	if err != nil {
+		return 0, err
+	}
+	if len(runInfo.OutputFiles) > 0 || runInfo.Partial {
+		return removeListed(outputDir, runInfo.OutputFiles, keep)
+	}
+
+	// Output directory is safe to clean, remove all files which are not kept.
+	removedCount, _, err := removeUnkept(outputDir, "", keep)
+	return removedCount, err
+}
+
+// removeListed removes the listed files from the output directory (unless they are kept),
+// then removes any parent directories which have become empty, and returns the number of files removed.
+func removeListed(outputDir string, relPaths []string, keep *KeepMatcher) (int, error) {
+	// Validate all paths before removing anything.
+	for _, relPath := range relPaths {
+		if !isSafeRelativePath(relPath) {
+			return 0, exitcode.New(exitcode.KindUnsafeOutputDir, "output directory '%s' is not safe to clean: marker lists invalid path '%s'", outputDir, relPath)
+		}
+	}
+
+	removedCount := 0
+	parentDirs := map[string]bool{}
+	for _, relPath := range relPaths {
+		if keep.Match(relPath, false) {
+			continue
+		}
+
+		err := os.Remove(filepath.Join(outputDir, filepath.FromSlash(relPath)))
+		if err != nil && !os.IsNotExist(err) {
+			return removedCount, err
+		}
+		if err == nil {
+			removedCount++
+		}
+
+		for dir := path.Dir(relPath); dir != "."; dir = path.Dir(dir) {
+			parentDirs[dir] = true
+		}
+	}
+
+	// Remove empty parent directories, deepest first.
+	dirs := make([]string, 0, len(parentDirs))
+	for dir := range parentDirs {
+		dirs = append(dirs, dir)
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		return strings.Count(dirs[i], "/") > strings.Count(dirs[j], "/")
+	})
+	for _, dir := range dirs {
+		dirPath := filepath.Join(outputDir, filepath.FromSlash(dir))
+		entries, err := os.ReadDir(dirPath)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return removedCount, err
+		}
+		if len(entries) == 0 {
+			err = os.Remove(dirPath)
+			if err != nil {
+				return removedCount, err
+			}
+		}
+	}
+
+	return removedCount, nil
+}
+
+// removeUnkept removes all entries under `filepath.Join(baseDir, relDir)` which are not kept,
+// and returns the number of files removed, and true if any entries were kept.
+func removeUnkept(baseDir string, relDir string, keep *KeepMatcher) (int, bool, error) {
+	entries, err := os.ReadDir(filepath.Join(baseDir, relDir))
+	if err != nil {
+		return 0, false, err
+	}
+
+	removedCount := 0
+	keptAny := false
+	for _, entry := range entries {
+		relPath := filepath.Join(relDir, entry.Name())
+
+		// skip entries that are kept
+		if keep.Match(relPath, entry.IsDir()) {
+			keptAny = true
+			continue
+		}
+
+		// recurse into directories, so that kept descendants are preserved
+		if entry.IsDir() && keep.HasPatterns() {
+			childRemovedCount, keptChild, err := removeUnkept(baseDir, relPath, keep)
+			removedCount += childRemovedCount
+			if err != nil {
+				return removedCount, false, err
+			}
+			if keptChild {
+				keptAny = true
+				continue
+			}
+		}
+
+		// count the files we are about to remove
+		if entry.IsDir() {
+			dirFiles, err := ListFiles(filepath.Join(baseDir, relPath))
+			if err != nil {
+				return removedCount, false, err
+			}
+			removedCount += len(dirFiles)
+		} else {
+			removedCount++
+		}
+
+		err = os.RemoveAll(filepath.Join(baseDir, relPath))
+		if err != nil {
+			return removedCount, false, err
+		}
+	}
+
+	return removedCount, keptAny, nil
+}
+
+// PublishOutput copies the rendered files from the staging directory into the output directory,
+// any paths matched by the KeepMatcher are not overwritten.
+// Returns the slash-separated relative paths of all files that were written, and all files that were skipped.
+func PublishOutput(stagingDir string, outputDir string, keep *KeepMatcher) ([]string, []string, error) {
+	// Check if anything was rendered, and return if not.
+	stagingDirExists, err := DirectoryExists(stagingDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !stagingDirExists {
+		return nil, nil, nil
 	}
 
-	// Output directory is safe to clean, remove all files.
-	for _, file := range files {
-		err = os.RemoveAll(filepath.Join(outputDir, file.Name()))
+	var writtenFiles []string
+	var skippedFiles []string
+	err = filepath.Walk(stagingDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
+
+		relPath, err := filepath.Rel(stagingDir, path)
+		if err != nil {
+			return err
+		}
+		if relPath == "." {
+			return nil
+		}
+
+		// don't overwrite kept paths
+		if keep.Match(relPath, info.IsDir()) {
+			if !info.IsDir() {
+				skippedFiles = append(skippedFiles, filepath.ToSlash(relPath))
+			}
+			return nil
+		}
+
+		destPath := filepath.Join(outputDir, relPath)
+		if info.IsDir() {
+			return os.MkdirAll(destPath, 0755)
+		}
+		writtenFiles = append(writtenFiles, filepath.ToSlash(relPath))
+		return copyFile(path, destPath)
+	})
+	if err != nil {
+		return nil, nil, err
 	}
 
-	return nil
+	return writtenFiles, skippedFiles, nil
+}
+
+// ReadMarkerFile reads the RunInfo JSON from the marker file in the output directory.
+func ReadMarkerFile(outputDir string) (*RunInfo, error) {
+	data, err := os.ReadFile(filepath.Join(outputDir, DeployKFOutputMarker))
+	if err != nil {
+		return nil, err
+	}
+
+	// NOTE: markers from older versions of the CLI may be empty or invalid, so we don't fail on parse errors
+	runInfo := &RunInfo{}
+	if len(data) > 0 {
+		_ = json.Unmarshal(data, runInfo)
+	}
+
+	return runInfo, nil
 }
 
 // CreateMarkerFile creates a marker file with RunInfo JSON in the output directory.
-func CreateMarkerFile(outputDir string, sourceVersion string, sourcePath string, sourceHash string, cliVersion string) error {
+func CreateMarkerFile(outputDir string, sourceVersion string, sourcePath string, sourceHash string, cliVersion string) (*RunInfo, error) {
 	// Check if the output folder exists, and create it if not.
 	outputDirExists, err := DirectoryExists(outputDir)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if !outputDirExists {
 		err = os.MkdirAll(outputDir, 0755)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
 	// Create the RunInfo struct.
-	runInfo := RunInfo{
+	runInfo := &RunInfo{
 		GeneratedAt:   time.Now().UTC().Format(time.RFC3339),
 		SourceVersion: sourceVersion,
 		SourcePath:    sourcePath,
@@ -86,6 +288,16 @@ func CreateMarkerFile(outputDir string, sourceVersion string, sourcePath string,
 		CLIVersion:    cliVersion,
 	}
 
+	err = WriteMarkerFile(outputDir, runInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	return runInfo, nil
+}
+
+// WriteMarkerFile writes the provided RunInfo to the marker file in the output directory, replacing any existing marker.
+func WriteMarkerFile(outputDir string, runInfo *RunInfo) error {
 	// Serialize the struct to JSON.
 	data, err := json.MarshalIndent(runInfo, "", "  ")
 	if err != nil {
@@ -0,0 +1,108 @@
+package generate
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/deployKF/cli/internal/exitcode"
+)
+
+// CheckOutputDirectorySafety returns an error if the output directory is a dangerous target for cleaning.
+//
+// The following directories are considered dangerous:
+//   - the filesystem root (this can never be allowed)
+//   - the user's home directory
+//   - any ancestor of the current working directory
+//   - the current working directory itself, unless it contains a `.deploykf_output` marker file
+//   - a directory containing a `.git` entry, unless that entry is kept by the KeepMatcher
+//
+// If allowUnsafe is true, all checks except the filesystem root are skipped.
+func CheckOutputDirectorySafety(outputDir string, keep *KeepMatcher, allowUnsafe bool) error {
+	absOutputDir, err := resolvePath(outputDir)
+	if err != nil {
+		return err
+	}
+
+	// the filesystem root is never safe to clean
+	if filepath.Dir(absOutputDir) == absOutputDir {
+		return exitcode.New(exitcode.KindUnsafeOutputDir, "output directory '%s' is not safe to clean: it is the filesystem root", outputDir)
+	}
+
+	if allowUnsafe {
+		return nil
+	}
+
+	// the user's home directory is not safe to clean
+	homeDir, err := os.UserHomeDir()
+	if err == nil {
+		absHomeDir, err := resolvePath(homeDir)
+		if err == nil && absHomeDir == absOutputDir {
+			return exitcode.New(exitcode.KindUnsafeOutputDir, "output directory '%s' is not safe to clean: it is the user's home directory", outputDir)
+		}
+	}
+
+	// the ancestors of the current working directory are not safe to clean, and the current working directory
+	// itself is only safe to clean if it was created by deployKF (like after `deploykf generate -O .`)
+	workingDir, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	absWorkingDir, err := resolvePath(workingDir)
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(absWorkingDir, absOutputDir+string(os.PathSeparator)) {
+		return exitcode.New(exitcode.KindUnsafeOutputDir, "output directory '%s' is not safe to clean: it contains the current working directory", outputDir)
+	}
+	if absWorkingDir == absOutputDir {
+		markerExists, err := FileExists(filepath.Join(absOutputDir, DeployKFOutputMarker))
+		if err != nil {
+			return err
+		}
+		if !markerExists {
+			return exitcode.New(exitcode.KindUnsafeOutputDir, "output directory '%s' is not safe to clean: it is the current working directory, and has no '%s' marker", outputDir, DeployKFOutputMarker)
+		}
+	}
+
+	// directories containing a `.git` entry are not safe to clean, unless the entry is kept
+	gitPath := filepath.Join(absOutputDir, ".git")
+	gitIsDir, gitIsFile, err := PathExists(gitPath)
+	if err != nil {
+		return err
+	}
+	if (gitIsDir || gitIsFile) && !keep.Match(".git", gitIsDir) {
+		return exitcode.New(exitcode.KindUnsafeOutputDir, "output directory '%s' is not safe to clean: it contains a '.git' entry (use a keep pattern to preserve it)", outputDir)
+	}
+
+	return nil
+}
+
+// resolvePath returns the absolute path with all symlinks evaluated,
+// if the path does not exist, only the absolute path is returned.
+func resolvePath(path string) (string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	resolvedPath, err := filepath.EvalSymlinks(absPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return absPath, nil
+		}
+		return "", err
+	}
+	return resolvedPath, nil
+}
+
+// isSafeRelativePath returns true if the path is relative and does not escape its parent directory.
+func isSafeRelativePath(relPath string) bool {
+	if relPath == "" || filepath.IsAbs(relPath) || filepath.VolumeName(relPath) != "" {
+		return false
+	}
+	cleanPath := filepath.Clean(filepath.FromSlash(relPath))
+	if cleanPath == "." || cleanPath == ".." || strings.HasPrefix(cleanPath, ".."+string(os.PathSeparator)) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,161 @@
+package generate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/deployKF/cli/internal/exitcode"
+)
+
+// chdir changes the working directory for the rest of a test
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := os.Chdir(previous); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+// makeOutputDir creates a directory with the files, and a marker file if withMarker is true
+func makeOutputDir(t *testing.T, dir string, withMarker bool, files ...string) string {
+	t.Helper()
+	if withMarker {
+		files = append(files, DeployKFOutputMarker)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range files {
+		path := filepath.Join(dir, filepath.FromSlash(file))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// tempDir returns a temporary directory, with any symlinks resolved (like '/var' on macOS)
+func tempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestCheckOutputDirectorySafety(t *testing.T) {
+	base := tempDir(t)
+	t.Setenv("HOME", makeOutputDir(t, filepath.Join(base, "home"), true))
+	t.Setenv("USERPROFILE", filepath.Join(base, "home"))
+	chdir(t, makeOutputDir(t, filepath.Join(base, "work", "project"), true))
+
+	makeOutputDir(t, filepath.Join(base, "work"), true)
+	makeOutputDir(t, filepath.Join(base, "output"), true)
+	makeOutputDir(t, filepath.Join(base, "repo"), true, ".git/HEAD")
+	makeOutputDir(t, filepath.Join(base, "worktree"), true, ".git")
+
+	tests := []struct {
+		name        string
+		outputDir   string
+		keep        *KeepMatcher
+		allowUnsafe bool
+		wantErr     bool
+	}{
+		{"root", string(filepath.Separator), nil, false, true},
+		{"root with allow unsafe", string(filepath.Separator), nil, true, true},
+		{"home", filepath.Join(base, "home"), nil, false, true},
+		{"home with allow unsafe", filepath.Join(base, "home"), nil, true, false},
+		{"cwd with marker", ".", nil, false, false},
+		{"cwd with marker by absolute path", filepath.Join(base, "work", "project"), nil, false, false},
+		{"ancestor of cwd", filepath.Join(base, "work"), nil, false, true},
+		{"ancestor of cwd by relative path", "..", nil, false, true},
+		{"ancestor of cwd with allow unsafe", filepath.Join(base, "work"), nil, true, false},
+		{"other dir", filepath.Join(base, "output"), nil, false, false},
+		{"other dir which does not exist", filepath.Join(base, "missing"), nil, false, false},
+		{"git dir", filepath.Join(base, "repo"), nil, false, true},
+		{"git file", filepath.Join(base, "worktree"), nil, false, true},
+		{"git dir which is kept", filepath.Join(base, "repo"), newPatternMatcher([]string{".git/"}), false, false},
+		{"git dir with allow unsafe", filepath.Join(base, "repo"), nil, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckOutputDirectorySafety(tt.outputDir, tt.keep, tt.allowUnsafe)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckOutputDirectorySafety(%q) error = %v, wantErr %v", tt.outputDir, err, tt.wantErr)
+			}
+			if err != nil && exitcode.KindOf(err) != exitcode.KindUnsafeOutputDir {
+				t.Errorf("CheckOutputDirectorySafety(%q) error kind = %v, want %v", tt.outputDir, exitcode.KindOf(err), exitcode.KindUnsafeOutputDir)
+			}
+		})
+	}
+}
+
+func TestCheckOutputDirectorySafetyCwdWithoutMarker(t *testing.T) {
+	chdir(t, makeOutputDir(t, filepath.Join(tempDir(t), "project"), false, "main.go"))
+
+	err := CheckOutputDirectorySafety(".", nil, false)
+	if exitcode.KindOf(err) != exitcode.KindUnsafeOutputDir {
+		t.Errorf("CheckOutputDirectorySafety(\".\") error = %v, want an unsafe output dir error", err)
+	}
+}
+
+func TestCleanOutputDirectoryCwd(t *testing.T) {
+	outputDir := makeOutputDir(t, filepath.Join(tempDir(t), "output"), true, "a.yaml", "sub/b.yaml")
+	chdir(t, outputDir)
+
+	removedCount, err := CleanOutputDirectory(".", nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// NOTE: the marker itself is not kept, it is replaced by the next run
+	if removedCount != 3 {
+		t.Errorf("CleanOutputDirectory() removed %d files, want 3", removedCount)
+	}
+}
+
+func TestCleanOutputDirectoryCopiedMarker(t *testing.T) {
+	base := tempDir(t)
+	chdir(t, base)
+
+	// a marker which was copied from elsewhere (listing paths outside the output directory) is refused
+	outputDir := makeOutputDir(t, filepath.Join(base, "output"), false, "a.yaml")
+	victim := makeOutputDir(t, filepath.Join(base, "victim"), false, "important.txt")
+	err := WriteMarkerFile(outputDir, &RunInfo{OutputFiles: []string{"a.yaml", "../victim/important.txt"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = CleanOutputDirectory(outputDir, nil, false)
+	if exitcode.KindOf(err) != exitcode.KindUnsafeOutputDir {
+		t.Errorf("CleanOutputDirectory() error = %v, want an unsafe output dir error", err)
+	}
+	for _, path := range []string{filepath.Join(outputDir, "a.yaml"), filepath.Join(victim, "important.txt")} {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("expected '%s' to still exist: %v", path, err)
+		}
+	}
+
+	// a marker which was copied into a dangerous directory doesn't make it safe to clean
+	home := makeOutputDir(t, filepath.Join(base, "home"), true, "notes.txt")
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	_, err = CleanOutputDirectory(home, nil, false)
+	if exitcode.KindOf(err) != exitcode.KindUnsafeOutputDir {
+		t.Errorf("CleanOutputDirectory(home) error = %v, want an unsafe output dir error", err)
+	}
+	if _, err := os.Stat(filepath.Join(home, "notes.txt")); err != nil {
+		t.Errorf("expected the home directory to be untouched: %v", err)
+	}
+}
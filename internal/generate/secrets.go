@@ -0,0 +1,203 @@
+package generate
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ValuesSchemaFile is the name of the optional JSON schema for values in the generator source.
+	ValuesSchemaFile = "values_schema.json"
+
+	// SensitiveSchemaFlag is the JSON schema extension which marks a values key as sensitive.
+	SensitiveSchemaFlag = "x-deploykf-sensitive"
+)
+
+// credentialPatterns are regular expressions which match common credential formats.
+var credentialPatterns = []struct {
+	name   string
+	regexp *regexp.Regexp
+}{
+	{"private key", regexp.MustCompile(`-----BEGIN ([A-Z]+ )?PRIVATE KEY-----`)},
+	{"AWS access key", regexp.MustCompile(`\b(AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{"GitHub token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,}\b`)},
+	{"Slack token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}\b`)},
+	{"URL with password", regexp.MustCompile(`[a-zA-Z][a-zA-Z0-9+.-]*://[^/\s:@]+:[^/\s@]+@`)},
+}
+
+// credentialKeyRegex matches ConfigMap keys which commonly hold credentials.
+var credentialKeyRegex = regexp.MustCompile(`(?i)(password|passwd|secret|token|api[_-]?key|access[_-]?key|private[_-]?key|client[_-]?secret)`)
+
+// SecretFinding describes a possible secret leak in a generated file.
+// NOTE: findings never contain the value of the secret itself
+type SecretFinding struct {
+	File      string `json:"file"`
+	Kind      string `json:"kind,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Field     string `json:"field"`
+	Rule      string `json:"rule"`
+	Message   string `json:"message"`
+}
+
+func (f SecretFinding) String() string {
+	object := f.Kind + "/" + f.Name
+	if f.Namespace != "" {
+		object = f.Kind + "/" + f.Namespace + "/" + f.Name
+	}
+	return fmt.Sprintf("%s: %s: %s: %s", f.File, object, f.Field, f.Message)
+}
+
+// SensitiveValue is the value of a values key which is marked as sensitive.
+type SensitiveValue struct {
+	Key   string // the dot-separated values key
+	Value string // the value of the key
+}
+
+// ScanForSecrets scans the YAML files under the directory for possible secret leaks:
+//   - `Secret` objects with non-empty `data` or `stringData` literals
+//   - `ConfigMap` entries with credential-like keys or values
+//   - any string which exactly matches the value of a sensitive values key
+func ScanForSecrets(dir string, sensitiveValues []SensitiveValue) ([]SecretFinding, error) {
+	// index the sensitive values, ignoring empty values
+	sensitiveKeys := map[string]string{}
+	for _, sv := range sensitiveValues {
+		if sv.Value != "" {
+			sensitiveKeys[sv.Value] = sv.Key
+		}
+	}
+
+	var findings []SecretFinding
+	err := forEachYAMLDocument(dir, func(relPath string, document *yaml.Node, err error) error {
+		// NOTE: syntax errors are reported by `ValidateYAMLFiles`
+		if err != nil {
+			return nil
+		}
+		var obj interface{}
+		if err := document.Decode(&obj); err != nil {
+			return nil
+		}
+		if objMap, ok := obj.(map[string]interface{}); ok {
+			findings = append(findings, scanObject(relPath, objMap, sensitiveKeys)...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return findings, nil
+}
+
+// scanObject returns the findings for a single decoded Kubernetes object.
+func scanObject(relPath string, obj map[string]interface{}, sensitiveKeys map[string]string) []SecretFinding {
+	base := SecretFinding{File: relPath}
+	base.Kind, _ = obj["kind"].(string)
+	if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+		base.Name, _ = metadata["name"].(string)
+		base.Namespace, _ = metadata["namespace"].(string)
+	}
+
+	var findings []SecretFinding
+	add := func(field string, rule string, message string) {
+		finding := base
+		finding.Field = field
+		finding.Rule = rule
+		finding.Message = message
+		findings = append(findings, finding)
+	}
+
+	// Secret objects should not contain literal data
+	if base.Kind == "Secret" {
+		for _, dataField := range []string{"data", "stringData"} {
+			dataMap, _ := obj[dataField].(map[string]interface{})
+			for _, key := range sortedKeys(dataMap) {
+				if value, _ := dataMap[key].(string); value != "" {
+					add(dataField+"."+key, "secret-literal", "Secret contains a non-empty literal value")
+				}
+			}
+		}
+	}
+
+	// ConfigMap entries should not look like credentials
+	if base.Kind == "ConfigMap" {
+		dataMap, _ := obj["data"].(map[string]interface{})
+		for _, key := range sortedKeys(dataMap) {
+			value, _ := dataMap[key].(string)
+			if value == "" {
+				continue
+			}
+			if credentialKeyRegex.MatchString(key) {
+				add("data."+key, "configmap-credential-key", "ConfigMap key looks like it holds a credential")
+				continue
+			}
+			for _, pattern := range credentialPatterns {
+				if pattern.regexp.MatchString(value) {
+					add("data."+key, "configmap-credential-pattern", "ConfigMap value looks like a "+pattern.name)
+					break
+				}
+			}
+		}
+	}
+
+	// no string in any object should exactly match a sensitive value
+	if len(sensitiveKeys) > 0 {
+		walkStrings(obj, "", func(field string, value string) {
+			if key, ok := sensitiveKeys[value]; ok {
+				add(field, "sensitive-value", fmt.Sprintf("value matches the sensitive values key '%s'", key))
+			}
+		})
+	}
+
+	return findings
+}
+
+// walkStrings calls fn for every string value in a decoded YAML object.
+func walkStrings(value interface{}, field string, fn func(field string, value string)) {
+	switch v := value.(type) {
+	case string:
+		fn(field, v)
+	case map[string]interface{}:
+		for _, key := range sortedKeys(v) {
+			childField := key
+			if field != "" {
+				childField = field + "." + key
+			}
+			walkStrings(v[key], childField, fn)
+		}
+	case []interface{}:
+		for i, item := range v {
+			walkStrings(item, fmt.Sprintf("%s[%d]", field, i), fn)
+		}
+	}
+}
+
+// sortedKeys returns the keys of a map in sorted order.
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// FindSensitiveValues returns the values of all keys which are marked as sensitive in the values schema.
+// Only string values are returned, as other types are unlikely to be secrets.
+func FindSensitiveValues(sensitiveKeys []string, lookup func(key string) (interface{}, bool)) []SensitiveValue {
+	var sensitiveValues []SensitiveValue
+	for _, key := range sensitiveKeys {
+		value, ok := lookup(key)
+		if !ok {
+			continue
+		}
+		if str, ok := value.(string); ok && strings.TrimSpace(str) != "" {
+			sensitiveValues = append(sensitiveValues, SensitiveValue{Key: key, Value: str})
+		}
+	}
+	return sensitiveValues
+}
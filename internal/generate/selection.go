@@ -0,0 +1,79 @@
+package generate
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// TemplateSelector decides which templates are rendered, based on gitignore-style `--only` and `--skip` patterns.
+// Patterns are relative to the `templates` folder of the generator source.
+type TemplateSelector struct {
+	only *KeepMatcher
+	skip *KeepMatcher
+}
+
+// NewTemplateSelector creates a TemplateSelector, a template is selected if it matches any of the `only` patterns
+// (or there are none), and does not match any of the `skip` patterns.
+func NewTemplateSelector(only []string, skip []string) *TemplateSelector {
+	s := &TemplateSelector{}
+	if len(only) > 0 {
+		s.only = newPatternMatcher(only)
+	}
+	if len(skip) > 0 {
+		s.skip = newPatternMatcher(skip)
+	}
+	return s
+}
+
+// IsPartial returns true if some templates may not be selected.
+func (s *TemplateSelector) IsPartial() bool {
+	return s.only != nil || s.skip != nil
+}
+
+// Selected returns true if the provided slash-separated template path should be rendered.
+func (s *TemplateSelector) Selected(relPath string) bool {
+	if s.only != nil && !s.only.Match(relPath, false) {
+		return false
+	}
+	if s.skip != nil && s.skip.Match(relPath, false) {
+		return false
+	}
+	return true
+}
+
+// RemoveUnselectedTemplates removes the templates which are not selected from the templates folder,
+// and returns the number of selected and removed templates.
+// This is layered on top of the `.gomplateignore` rules of the generator,
+// so it can never cause a template which is ignored by the generator to be rendered.
+func RemoveUnselectedTemplates(templatesDir string, selector *TemplateSelector) (int, int, error) {
+	if !selector.IsPartial() {
+		return 0, 0, nil
+	}
+
+	files, err := ListFiles(templatesDir)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	selectedCount := 0
+	removedCount := 0
+	for _, relPath := range files {
+		// the ignore files are not templates, and must be kept so the generator's rules still apply
+		base := path.Base(relPath)
+		if base == ".gomplateignore" || base == ".gomplateignore_template" {
+			continue
+		}
+		if selector.Selected(relPath) {
+			selectedCount++
+			continue
+		}
+		err = os.Remove(filepath.Join(templatesDir, filepath.FromSlash(relPath)))
+		if err != nil {
+			return selectedCount, removedCount, err
+		}
+		removedCount++
+	}
+
+	return selectedCount, removedCount, nil
+}
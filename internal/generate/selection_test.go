@@ -0,0 +1,112 @@
+package generate
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestTemplateSelectorSelected(t *testing.T) {
+	tests := []struct {
+		name string
+		only []string
+		skip []string
+		path string
+		want bool
+	}{
+		{"no patterns", nil, nil, "manifests/app.yaml", true},
+
+		// --only
+		{"only dir with trailing slash", []string{"manifests/argocd/"}, nil, "manifests/argocd/app.yaml", true},
+		{"only dir with trailing slash nested", []string{"manifests/argocd/"}, nil, "manifests/argocd/apps/a/b.yaml", true},
+		{"only dir with trailing slash other dir", []string{"manifests/argocd/"}, nil, "manifests/kubeflow/app.yaml", false},
+		{"only dir name at any depth", []string{"argocd/"}, nil, "manifests/argocd/app.yaml", true},
+		{"only dir name is not a file", []string{"argocd/"}, nil, "manifests/argocd", false},
+		{"only anchored dir", []string{"/manifests/"}, nil, "manifests/a.yaml", true},
+		{"only anchored dir nested", []string{"/manifests/"}, nil, "other/manifests/a.yaml", false},
+		{"only double star", []string{"manifests/**/kustomization.yaml"}, nil, "manifests/a/b/kustomization.yaml", true},
+		{"only double star other file", []string{"manifests/**/kustomization.yaml"}, nil, "manifests/a/b/app.yaml", false},
+		{"only any of many", []string{"a/", "b/"}, nil, "b/x.yaml", true},
+
+		// --skip
+		{"skip dir", nil, []string{"kubeflow-tools/"}, "manifests/kubeflow-tools/pipelines/a.yaml", false},
+		{"skip other dir", nil, []string{"kubeflow-tools/"}, "manifests/deploykf-core/a.yaml", true},
+		{"skip glob", nil, []string{"*.md"}, "docs/README.md", false},
+
+		// --skip wins over --only
+		{"skip wins over only", []string{"manifests/"}, []string{"manifests/argocd/"}, "manifests/argocd/app.yaml", false},
+		{"skip wins over only same pattern", []string{"a.yaml"}, []string{"a.yaml"}, "a.yaml", false},
+		{"only without skip match", []string{"manifests/"}, []string{"manifests/argocd/"}, "manifests/kubeflow/app.yaml", true},
+
+		// negation inside a list of patterns
+		{"only with negation", []string{"*.yaml", "!b.yaml"}, nil, "manifests/b.yaml", false},
+		{"only with negation other file", []string{"*.yaml", "!b.yaml"}, nil, "manifests/a.yaml", true},
+		{"skip with negation", nil, []string{"*.yaml", "!keep.yaml"}, "sub/keep.yaml", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewTemplateSelector(tt.only, tt.skip).Selected(tt.path)
+			if got != tt.want {
+				t.Errorf("Selected(%q) with only %q and skip %q = %v, want %v", tt.path, tt.only, tt.skip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateSelectorIsPartial(t *testing.T) {
+	if NewTemplateSelector(nil, nil).IsPartial() {
+		t.Errorf("IsPartial() without patterns = true, want false")
+	}
+	if !NewTemplateSelector([]string{"a/"}, nil).IsPartial() {
+		t.Errorf("IsPartial() with --only = false, want true")
+	}
+	if !NewTemplateSelector(nil, []string{"a/"}).IsPartial() {
+		t.Errorf("IsPartial() with --skip = false, want true")
+	}
+}
+
+func TestRemoveUnselectedTemplates(t *testing.T) {
+	templatesDir := t.TempDir()
+	for _, relPath := range []string{
+		".gomplateignore_template",
+		"manifests/argocd/app.yaml",
+		"manifests/argocd/.gomplateignore",
+		"manifests/kubeflow/pipelines/a.yaml",
+		"manifests/kubeflow/pipelines/b.yaml",
+		"manifests/kubeflow/notebooks/c.yaml",
+	} {
+		path := filepath.Join(templatesDir, filepath.FromSlash(relPath))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	selector := NewTemplateSelector([]string{"manifests/kubeflow/"}, []string{"notebooks/"})
+	selectedCount, removedCount, err := RemoveUnselectedTemplates(templatesDir, selector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if selectedCount != 2 || removedCount != 2 {
+		t.Errorf("RemoveUnselectedTemplates() = (%d, %d), want (2, 2)", selectedCount, removedCount)
+	}
+
+	remaining, err := ListFiles(templatesDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(remaining)
+	want := []string{
+		".gomplateignore_template",
+		"manifests/argocd/.gomplateignore",
+		"manifests/kubeflow/pipelines/a.yaml",
+		"manifests/kubeflow/pipelines/b.yaml",
+	}
+	if !reflect.DeepEqual(remaining, want) {
+		t.Errorf("remaining templates = %q, want %q", remaining, want)
+	}
+}
@@ -9,8 +9,16 @@ import (
 	"path/filepath"
 
 	"github.com/google/go-github/v50/github"
+
+	"github.com/deployKF/cli/internal/exitcode"
 )
 
+// SourceLogger is the logger used while downloading a generator source.
+type SourceLogger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+}
+
 type SourceHelper struct {
 	GithubOwner             string // the owner of the generator source GitHub repository
 	GithubRepo              string // the name of the generator source GitHub repository
@@ -51,25 +59,26 @@ func NewSourceHelper(opts ...SourceHelperOptions) *SourceHelper {
 
 // DownloadAndUnpackSource downloads the generator source artifact for the specified version (if it's not already cached),
 // unpacks it to the provided folder, then returns the local path of the artifact .zip file.
-func (h *SourceHelper) DownloadAndUnpackSource(version string, unpackTargetDir string, out io.Writer) (string, error) {
+func (h *SourceHelper) DownloadAndUnpackSource(ctx context.Context, version string, unpackTargetDir string, log SourceLogger) (string, error) {
 	assetsCacheDir, err := h.prepareAssetsCacheDir()
 	if err != nil {
 		return "", err
 	}
 
 	// download the artifact, if it's not cached
-	artifactName := h.GeneratorArtifactPrefix + version + h.GeneratorArtifactSuffix
+	artifactName := h.ArtifactName(version)
 	artifactIsCached, artifactPath, err := h.isArtifactCached(assetsCacheDir, artifactName)
 	if err != nil {
 		return "", err
 	}
+	log.Debugf("generator source artifact path: %s (cached: %t)", artifactPath, artifactIsCached)
 	if !artifactIsCached {
-		fmt.Fprintf(out, "Downloading deployKF generator source version '%s' from github repo '%s/%s'\n", version, h.GithubOwner, h.GithubRepo)
+		log.Infof("Downloading deployKF generator source version '%s' from github repo '%s/%s'", version, h.GithubOwner, h.GithubRepo)
 
 		// get the GitHub release for the specified version
-		githubRelease, err := h.getReleaseByVersion(version)
+		githubRelease, err := h.getReleaseByVersion(ctx, version)
 		if err != nil {
-			return "", err
+			return "", exitcode.Wrap(exitcode.KindDownloadFailed, err)
 		}
 
 		// find the artifact in the release
@@ -81,19 +90,19 @@ func (h *SourceHelper) DownloadAndUnpackSource(version string, unpackTargetDir s
 			}
 		}
 		if githubAsset == nil {
-			return "", fmt.Errorf("generator artifact '%s' not found in release '%s'", artifactName, *githubRelease.TagName)
+			return "", exitcode.New(exitcode.KindSourceNotFound, "generator artifact '%s' not found in release '%s'", artifactName, *githubRelease.TagName)
 		}
 
 		// download the artifact
-		err = h.downloadReleaseAsset(githubAsset, artifactPath)
+		err = h.downloadReleaseAsset(ctx, githubAsset, artifactPath)
 		if err != nil {
-			return "", err
+			return "", exitcode.Wrap(exitcode.KindDownloadFailed, err)
 		}
 	}
 
 	// unzip the artifact
-	fmt.Fprintf(out, "Using cached deployKF generator source: %s\n", artifactPath)
-	err = UnzipFile(artifactPath, unpackTargetDir, "generator")
+	log.Infof("Using cached deployKF generator source: %s", artifactPath)
+	err = UnzipFile(ctx, artifactPath, unpackTargetDir, "generator")
 	if err != nil {
 		return "", err
 	}
@@ -101,6 +110,17 @@ func (h *SourceHelper) DownloadAndUnpackSource(version string, unpackTargetDir s
 	return artifactPath, nil
 }
 
+// ReleaseTag returns the GitHub release tag of a version (without a "v" prefix), like "v0.1.4" for "0.1.4".
+func (h *SourceHelper) ReleaseTag(version string) string {
+	// the repo uses a "v" prefix for release tags
+	return "v" + version
+}
+
+// ArtifactName returns the name of the generator source zip artifact of a version (without a "v" prefix).
+func (h *SourceHelper) ArtifactName(version string) string {
+	return h.GeneratorArtifactPrefix + version + h.GeneratorArtifactSuffix
+}
+
 // prepareAssetsCacheDir creates the assets cache directory (if it doesn't exist), and returns the path.
 func (h *SourceHelper) prepareAssetsCacheDir() (string, error) {
 	// use an os-specific cache directory for the downloaded artifact
@@ -130,38 +150,49 @@ func (h *SourceHelper) isArtifactCached(assetsCacheDir string, artifactName stri
 }
 
 // downloadReleaseAsset downloads the specified release asset to the provided path.
-func (h *SourceHelper) downloadReleaseAsset(releaseAsset *github.ReleaseAsset, downloadPath string) error {
-	resp, err := http.Get(*releaseAsset.BrowserDownloadURL)
+// The asset is downloaded into a temporary file which is renamed when complete, so an interrupted download
+// never leaves a partial artifact in the cache.
+func (h *SourceHelper) downloadReleaseAsset(ctx context.Context, releaseAsset *github.ReleaseAsset, downloadPath string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, *releaseAsset.BrowserDownloadURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to download '%s': %s", *releaseAsset.BrowserDownloadURL, resp.Status)
+	}
 
-	out, err := os.Create(downloadPath)
+	out, err := os.CreateTemp(filepath.Dir(downloadPath), filepath.Base(downloadPath)+".*.part")
 	if err != nil {
 		return err
 	}
-	defer out.Close()
+	defer os.Remove(out.Name())
 
 	_, err = io.Copy(out, resp.Body)
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return os.Rename(out.Name(), downloadPath)
 }
 
 // getReleaseByVersion returns the `github.RepositoryRelease` corresponding to the specified version.
-func (h *SourceHelper) getReleaseByVersion(version string) (*github.RepositoryRelease, error) {
+func (h *SourceHelper) getReleaseByVersion(ctx context.Context, version string) (*github.RepositoryRelease, error) {
 	client := github.NewClient(nil)
 
-	// the repo uses a "v" prefix for release tags
-	tagName := "v" + version
+	tagName := h.ReleaseTag(version)
 
-	release, resp, err := client.Repositories.GetReleaseByTag(context.Background(), h.GithubOwner, h.GithubRepo, tagName)
+	release, resp, err := client.Repositories.GetReleaseByTag(ctx, h.GithubOwner, h.GithubRepo, tagName)
 	if err != nil {
 		if resp != nil && resp.StatusCode == http.StatusNotFound {
-			return nil, fmt.Errorf("no github release found with tag '%s'", tagName)
+			return nil, exitcode.New(exitcode.KindSourceNotFound, "no github release found with tag '%s'", tagName)
 		}
 		return nil, err
 	}
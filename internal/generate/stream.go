@@ -0,0 +1,93 @@
+package generate
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// IsYAMLFile returns true if the path has a `.yaml` or `.yml` extension.
+func IsYAMLFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// WriteYAMLStream writes every YAML file under the source directory into a single multi-document YAML stream,
+// each document is separated by `---` and prefixed with a `# Source: <relative path>` comment.
+// Returns the slash-separated relative paths of any non-YAML files which were skipped.
+func WriteYAMLStream(srcDir string, w io.Writer) ([]string, error) {
+	files, err := ListFiles(srcDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var skippedFiles []string
+	bw := bufio.NewWriter(w)
+	for _, relPath := range files {
+		if !IsYAMLFile(relPath) {
+			skippedFiles = append(skippedFiles, relPath)
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(srcDir, filepath.FromSlash(relPath)))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, doc := range splitYAMLDocuments(data) {
+			_, err = fmt.Fprintf(bw, "---\n# Source: %s\n%s\n", relPath, doc)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	err = bw.Flush()
+	if err != nil {
+		return nil, err
+	}
+
+	return skippedFiles, nil
+}
+
+// yamlDocumentMarkerRegex matches the lines which start (`---`) or end (`...`) a YAML document,
+// which may be followed by content on the same line (like `--- # comment` or `--- !!map`)
+var yamlDocumentMarkerRegex = regexp.MustCompile(`^(---|\.\.\.)(\s|$)`)
+
+// splitYAMLDocuments splits the data on `---` (and `...`) marker lines, and returns the non-empty documents.
+// Any content after a `---` marker is kept at the start of the next document.
+func splitYAMLDocuments(data []byte) []string {
+	var docs []string
+	var current []string
+
+	flush := func() {
+		doc := strings.TrimRight(strings.Join(current, "\n"), "\n")
+		if strings.TrimSpace(doc) != "" {
+			docs = append(docs, doc)
+		}
+		current = nil
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), len(data)+1)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if yamlDocumentMarkerRegex.MatchString(line) {
+			flush()
+			rest := strings.TrimSpace(line[3:])
+			if strings.HasPrefix(line, "---") && rest != "" {
+				current = append(current, rest)
+			}
+			continue
+		}
+		current = append(current, line)
+	}
+	flush()
+
+	return docs
+}
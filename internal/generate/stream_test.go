@@ -0,0 +1,97 @@
+package generate
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestSplitYAMLDocuments(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []string
+	}{
+		{"single document", "a: 1\n", []string{"a: 1"}},
+		{"empty", "", nil},
+		{"only separators", "---\n---\n", nil},
+		{"two documents", "a: 1\n---\nb: 2\n", []string{"a: 1", "b: 2"}},
+		{"leading separator", "---\na: 1\n", []string{"a: 1"}},
+		{"trailing separator", "a: 1\n---\n", []string{"a: 1"}},
+		{"separator with trailing spaces", "a: 1\n---  \nb: 2\n", []string{"a: 1", "b: 2"}},
+		{"separator with tab", "a: 1\n---\t\nb: 2\n", []string{"a: 1", "b: 2"}},
+		{"separator with CRLF", "a: 1\r\n---\r\nb: 2\r\n", []string{"a: 1", "b: 2"}},
+		{"separator with comment", "a: 1\n--- # the second document\nb: 2\n", []string{"a: 1", "# the second document\nb: 2"}},
+		{"separator with content", "a: 1\n--- {b: 2}\n", []string{"a: 1", "{b: 2}"}},
+		{"separator with tag", "--- !!map\na: 1\n", []string{"!!map\na: 1"}},
+		{"separator with block scalar", "--- |\n  text\n", []string{"|\n  text"}},
+		{"document end marker", "a: 1\n...\nb: 2\n", []string{"a: 1", "b: 2"}},
+		{"document end marker then separator", "a: 1\n...\n---\nb: 2\n", []string{"a: 1", "b: 2"}},
+		{"longer dashes are content", "a: |\n  x\n----\n", []string{"a: |\n  x\n----"}},
+		{"dashes within a line are content", "a: ---\n", []string{"a: ---"}},
+		{"indented separator is content", "a: |\n  ---\n  b\n", []string{"a: |\n  ---\n  b"}},
+		{"dashes without space are content", "---a: 1\n", []string{"---a: 1"}},
+		{"comment only document", "a: 1\n---\n# just a comment\n", []string{"a: 1", "# just a comment"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitYAMLDocuments([]byte(tt.data))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitYAMLDocuments(%q) = %q, want %q", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteYAMLStream(t *testing.T) {
+	srcDir := t.TempDir()
+	files := map[string]string{
+		"a.yaml":      "kind: A\n--- # second\nkind: B\n",
+		"b/c.yml":     "--- {kind: C}\n...\n",
+		"b/notes.txt": "not yaml\n",
+		"empty.yaml":  "---\n",
+		"z/last.yaml": "kind: D\n",
+	}
+	for relPath, data := range files {
+		path := filepath.Join(srcDir, filepath.FromSlash(relPath))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var buf bytes.Buffer
+	skipped, err := WriteYAMLStream(srcDir, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(skipped, []string{"b/notes.txt"}) {
+		t.Errorf("skipped = %q, want [b/notes.txt]", skipped)
+	}
+
+	// the stream is valid YAML, with one document per input document
+	var kinds []string
+	decoder := yaml.NewDecoder(&buf)
+	for {
+		var doc map[string]interface{}
+		err := decoder.Decode(&doc)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("the stream is not valid YAML: %v", err)
+		}
+		kinds = append(kinds, doc["kind"].(string))
+	}
+	if want := []string{"A", "B", "C", "D"}; !reflect.DeepEqual(kinds, want) {
+		t.Errorf("stream kinds = %q, want %q", kinds, want)
+	}
+}
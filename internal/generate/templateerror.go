@@ -0,0 +1,177 @@
+package generate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// templateContextLines is the number of lines shown before and after the failing line of a template.
+const templateContextLines = 2
+
+var (
+	// templateLocationRegex matches the location in a text/template error, like "template: templates/app.yaml:12:5: "
+	templateLocationRegex = regexp.MustCompile(`template: ([^\s:]+):(\d+)(?::(\d+))?: `)
+
+	// templateExecutingRegex matches the expression of a text/template execution error, like `executing "name" at <.Values.foo>: `
+	templateExecutingRegex = regexp.MustCompile(`executing "[^"]*" at <([^>]*)>: `)
+
+	// missingKeyRegex matches the errors for a missing key, and captures the key
+	missingKeyRegex = regexp.MustCompile(`map has no entry for key "([^"]*)"|can't evaluate field (\S+) in type`)
+)
+
+// TemplateError is an error from rendering a template in the generator source.
+type TemplateError struct {
+	File      string   // the path of the template, relative to the generator source (like "templates/app/deployment.yaml")
+	Line      int      // the failing line (1-based), or 0 if unknown
+	Column    int      // the failing column (1-based), or 0 if unknown
+	Message   string   // the error message, without the location
+	ValuesKey string   // the dot-separated values key being accessed (only for missing key errors)
+	Context   []string // the lines of the template around the failing line, formatted like "> 12 | text"
+	Err       error    // the original error
+}
+
+func (e *TemplateError) Error() string {
+	var b strings.Builder
+	b.WriteString("failed to render template '")
+	b.WriteString(e.File)
+	b.WriteString("'")
+	if e.Line > 0 {
+		fmt.Fprintf(&b, " at line %d", e.Line)
+		if e.Column > 0 {
+			fmt.Fprintf(&b, ", column %d", e.Column)
+		}
+	}
+	b.WriteString(": ")
+	b.WriteString(e.Message)
+	if e.ValuesKey != "" {
+		fmt.Fprintf(&b, " (values key: '%s')", e.ValuesKey)
+	}
+	for _, line := range e.Context {
+		b.WriteString("\n")
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+func (e *TemplateError) Unwrap() error {
+	return e.Err
+}
+
+// RewriteTemplateError converts an error from gomplate into a TemplateError, with paths relative to the sourceDirs,
+// and a few lines of context from the failing template.
+// If the error does not refer to a template in one of the sourceDirs, it is returned unchanged.
+func RewriteTemplateError(err error, sourceDirs ...string) error {
+	if err == nil {
+		return nil
+	}
+	var templateErr *TemplateError
+	if errors.As(err, &templateErr) {
+		return err
+	}
+
+	// make all paths relative to the generator source
+	message := err.Error()
+	for _, sourceDir := range sourceDirs {
+		message = strings.ReplaceAll(message, filepath.ToSlash(sourceDir)+"/", "")
+		message = strings.ReplaceAll(message, sourceDir+string(filepath.Separator), "")
+	}
+
+	// find the location of the error
+	location := templateLocationRegex.FindStringSubmatchIndex(message)
+	if location == nil {
+		return err
+	}
+	templateErr = &TemplateError{
+		File: message[location[2]:location[3]],
+		Err:  err,
+	}
+	templateErr.Line, _ = strconv.Atoi(message[location[4]:location[5]])
+	if location[6] >= 0 {
+		templateErr.Column, _ = strconv.Atoi(message[location[6]:location[7]])
+	}
+
+	// the message is everything after the location, but we keep the failing expression (if any)
+	detail := message[location[1]:]
+	expression := ""
+	if executing := templateExecutingRegex.FindStringSubmatchIndex(detail); executing != nil && executing[0] == 0 {
+		expression = detail[executing[2]:executing[3]]
+		detail = "at <" + expression + ">: " + detail[executing[1]:]
+	}
+	templateErr.Message = detail
+
+	// find the values key of a missing key error
+	if missingKey := missingKeyRegex.FindStringSubmatch(detail); missingKey != nil {
+		key := missingKey[1]
+		if key == "" {
+			key = missingKey[2]
+		}
+		templateErr.ValuesKey = valuesKeyFromExpression(expression, key)
+	}
+
+	// read the lines around the failing line of the template, from the first sourceDir which contains it
+	if templateErr.Line > 0 {
+		for _, sourceDir := range sourceDirs {
+			templateErr.Context = readTemplateContext(filepath.Join(sourceDir, filepath.FromSlash(templateErr.File)), templateErr.Line)
+			if templateErr.Context != nil {
+				break
+			}
+		}
+	}
+
+	return templateErr
+}
+
+// valuesKeyFromExpression returns the dot-separated values key of a template expression (like ".Values.foo.bar"),
+// up to and including the missing key, or "" if the expression does not access the values.
+func valuesKeyFromExpression(expression string, missingKey string) string {
+	expression = strings.TrimPrefix(expression, "$")
+	if !strings.HasPrefix(expression, ".Values.") {
+		return ""
+	}
+	// NOTE: the expression may contain a pipeline, like `.Values.foo | default "x"`
+	fields := strings.Split(strings.Fields(strings.TrimPrefix(expression, ".Values."))[0], ".")
+	for i, field := range fields {
+		if field == missingKey {
+			return strings.Join(fields[:i+1], ".")
+		}
+	}
+	return strings.Join(fields, ".")
+}
+
+// readTemplateContext returns the lines around the line of a template, formatted like "> 12 | text".
+// If the template can not be read, nil is returned.
+func readTemplateContext(path string, line int) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	lines := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if line > len(lines) {
+		return nil
+	}
+
+	first := line - templateContextLines
+	if first < 1 {
+		first = 1
+	}
+	last := line + templateContextLines
+	if last > len(lines) {
+		last = len(lines)
+	}
+	width := len(strconv.Itoa(last))
+
+	var context []string
+	for i := first; i <= last; i++ {
+		marker := " "
+		if i == line {
+			marker = ">"
+		}
+		context = append(context, fmt.Sprintf("  %s %*d | %s", marker, width, i, lines[i-1]))
+	}
+	return context
+}
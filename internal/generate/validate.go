@@ -0,0 +1,114 @@
+package generate
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlErrorLineRegex extracts the line number from YAML parser errors like "yaml: line 3: did not find expected key"
+var yamlErrorLineRegex = regexp.MustCompile(`^yaml: line (\d+): (.*)$`)
+
+// YAMLError describes a YAML syntax error in a generated file.
+type YAMLError struct {
+	File     string // the slash-separated path of the generated file, relative to the output directory
+	Template string // the slash-separated path of the originating template, relative to the generator source
+	Line     int    // the line of the error, or 0 if unknown
+	Message  string // the error message from the YAML parser
+}
+
+func (e YAMLError) String() string {
+	location := e.File
+	if e.Line > 0 {
+		location += ":" + strconv.Itoa(e.Line)
+	}
+	return fmt.Sprintf("%s: %s (template: %s)", location, e.Message, e.Template)
+}
+
+// ValidateYAMLFiles parses every `.yaml` and `.yml` file under the directory (which contains rendered templates),
+// and returns a YAMLError for each file which is not valid YAML.
+// The templatesPrefix is prepended to each file path to get the path of the originating template.
+func ValidateYAMLFiles(dir string, templatesPrefix string) ([]YAMLError, error) {
+	var yamlErrors []YAMLError
+	err := forEachYAMLDocument(dir, func(relPath string, document *yaml.Node, err error) error {
+		if err != nil {
+			yamlErr := parseYAMLError(err)
+			yamlErr.File = relPath
+			yamlErr.Template = path.Join(templatesPrefix, relPath)
+			yamlErrors = append(yamlErrors, *yamlErr)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return yamlErrors, nil
+}
+
+// forEachYAMLDocument parses every `.yaml` and `.yml` file under the directory, and calls fn for each document
+// (with the slash-separated path of the file, relative to the directory).
+// If a file is not valid YAML, fn is called once with the syntax error (and a nil document),
+// and the rest of the file is skipped.
+// Iteration stops at the first error returned by fn.
+func forEachYAMLDocument(dir string, fn func(relPath string, document *yaml.Node, err error) error) error {
+	files, err := ListFiles(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, relPath := range files {
+		if !IsYAMLFile(relPath) {
+			continue
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(relPath)))
+		if err != nil {
+			return err
+		}
+
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		for {
+			document := &yaml.Node{}
+			err := decoder.Decode(document)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				if err := fn(relPath, nil, err); err != nil {
+					return err
+				}
+				break
+			}
+			if err := fn(relPath, document, nil); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseYAMLError converts a YAML parser error into a YAMLError.
+func parseYAMLError(err error) *YAMLError {
+	yamlErr := &YAMLError{Message: err.Error()}
+
+	// NOTE: the YAML parser only reports the line (not the column) of syntax errors, so we only report the line
+	matches := yamlErrorLineRegex.FindStringSubmatch(yamlErr.Message)
+	if matches != nil {
+		yamlErr.Line, _ = strconv.Atoi(matches[1])
+		yamlErr.Message = matches[2]
+	} else {
+		yamlErr.Message = strings.TrimPrefix(yamlErr.Message, "yaml: ")
+	}
+
+	return yamlErr
+}
@@ -0,0 +1,84 @@
+package generate
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestForEachYAMLDocument(t *testing.T) {
+	dir := t.TempDir()
+	for relPath, content := range map[string]string{
+		"a.yaml":       "kind: A\n---\nkind: B\n",
+		"b/bad.yml":    "kind: C\n---\nkey: [unclosed\n---\nkind: D\n",
+		"b/notes.txt":  "kind: E\n",
+		"c/empty.yaml": "",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(relPath))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var visited []string
+	err := forEachYAMLDocument(dir, func(relPath string, document *yaml.Node, err error) error {
+		if err != nil {
+			visited = append(visited, relPath+": error")
+			return nil
+		}
+		var obj struct {
+			Kind string `yaml:"kind"`
+		}
+		if err := document.Decode(&obj); err != nil {
+			t.Fatal(err)
+		}
+		visited = append(visited, relPath+": "+obj.Kind)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the rest of a file is skipped after a syntax error, and files which are not YAML are ignored
+	want := []string{"a.yaml: A", "a.yaml: B", "b/bad.yml: C", "b/bad.yml: error"}
+	if !reflect.DeepEqual(visited, want) {
+		t.Errorf("visited documents = %q, want %q", visited, want)
+	}
+
+	// an error from the callback stops the iteration
+	count := 0
+	err = forEachYAMLDocument(dir, func(relPath string, document *yaml.Node, err error) error {
+		count++
+		return os.ErrInvalid
+	})
+	if err != os.ErrInvalid || count != 1 {
+		t.Errorf("forEachYAMLDocument() = %v after %d documents, want %v after 1", err, count, os.ErrInvalid)
+	}
+}
+
+func TestValidateYAMLFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "good.yaml"), []byte("a: 1\n---\nb: 2\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "bad.yaml"), []byte("a: 1\nb: [\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	yamlErrors, err := ValidateYAMLFiles(dir, "templates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(yamlErrors) != 1 {
+		t.Fatalf("ValidateYAMLFiles() = %v, want 1 error", yamlErrors)
+	}
+	if yamlErrors[0].File != "bad.yaml" || yamlErrors[0].Template != "templates/bad.yaml" || yamlErrors[0].Line == 0 {
+		t.Errorf("ValidateYAMLFiles() = %+v, want an error in 'bad.yaml' with a line number", yamlErrors[0])
+	}
+}
@@ -2,9 +2,9 @@ package generate
 
 import (
 	"encoding/json"
-	"errors"
-	"fmt"
 	"os"
+
+	"github.com/deployKF/cli/internal/exitcode"
 )
 
 type GeneratorMarker struct {
@@ -15,17 +15,17 @@ type GeneratorMarker struct {
 func GetGeneratorSchemaVersion(markerPath string) (string, error) {
 	bytes, err := os.ReadFile(markerPath)
 	if err != nil {
-		return "", fmt.Errorf("failed to read generator marker file: %v", err)
+		return "", exitcode.New(exitcode.KindUnsupportedSource, "failed to read generator marker file: %v", err)
 	}
 
 	var marker GeneratorMarker
 	err = json.Unmarshal(bytes, &marker)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse generator marker file: %v", err)
+		return "", exitcode.New(exitcode.KindUnsupportedSource, "failed to parse generator marker file: %v", err)
 	}
 
 	if marker.GeneratorSchema == "" {
-		return "", errors.New("generator marker file is missing 'generator_schema' field")
+		return "", exitcode.New(exitcode.KindUnsupportedSource, "generator marker file is missing 'generator_schema' field")
 	}
 
 	return marker.GeneratorSchema, nil
@@ -41,7 +41,7 @@ func VerifyGeneratorSource(templatesPath string, helpersPath string, defaultValu
 		return err
 	}
 	if !markerFileExists {
-		return fmt.Errorf("invalid generator source: marker file is missing")
+		return exitcode.New(exitcode.KindUnsupportedSource, "invalid generator source: marker file is missing")
 	}
 
 	// Verify that we support the generator schema version
@@ -50,7 +50,7 @@ func VerifyGeneratorSource(templatesPath string, helpersPath string, defaultValu
 		return err
 	}
 	if generatorSchemaVersion != "v1" {
-		return fmt.Errorf("invalid generator source: unsupported schema version '%s'", generatorSchemaVersion)
+		return exitcode.New(exitcode.KindUnsupportedSource, "invalid generator source: unsupported schema version '%s'", generatorSchemaVersion)
 	}
 
 	// Verify that the templates directory exists
@@ -59,7 +59,7 @@ func VerifyGeneratorSource(templatesPath string, helpersPath string, defaultValu
 		return err
 	}
 	if !templatesDirExists {
-		return fmt.Errorf("invalid generator source: templates directory is missing")
+		return exitcode.New(exitcode.KindUnsupportedSource, "invalid generator source: templates directory is missing")
 	}
 
 	// Verify that the helpers directory exists
@@ -68,7 +68,7 @@ func VerifyGeneratorSource(templatesPath string, helpersPath string, defaultValu
 		return err
 	}
 	if !helpersDirExists {
-		return fmt.Errorf("invalid generator source: helpers directory is missing")
+		return exitcode.New(exitcode.KindUnsupportedSource, "invalid generator source: helpers directory is missing")
 	}
 
 	// Verify that the default values file exists
@@ -77,7 +77,7 @@ func VerifyGeneratorSource(templatesPath string, helpersPath string, defaultValu
 		return err
 	}
 	if !defaultValuesFileExists {
-		return fmt.Errorf("invalid generator source: default values file is missing")
+		return exitcode.New(exitcode.KindUnsupportedSource, "invalid generator source: default values file is missing")
 	}
 
 	return nil
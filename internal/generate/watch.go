@@ -0,0 +1,121 @@
+package generate
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// FileSnapshot records the size and modification time of every file under a set of watched paths.
+type FileSnapshot map[string]fileStamp
+
+type fileStamp struct {
+	size    int64
+	modTime time.Time
+}
+
+// TakeFileSnapshot returns a FileSnapshot of the provided paths, which may be files or folders.
+// Paths which don't exist are ignored, so that they are detected as changed when they are created.
+func TakeFileSnapshot(paths []string) (FileSnapshot, error) {
+	snapshot := FileSnapshot{}
+	for _, watchedPath := range paths {
+		err := filepath.WalkDir(watchedPath, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if d.IsDir() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			snapshot[path] = fileStamp{size: info.Size(), modTime: info.ModTime()}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return snapshot, nil
+}
+
+// ChangedPaths returns the sorted paths which were added, modified or removed since the other snapshot.
+func (s FileSnapshot) ChangedPaths(other FileSnapshot) []string {
+	var changed []string
+	for path, stamp := range s {
+		if otherStamp, ok := other[path]; !ok || !otherStamp.modTime.Equal(stamp.modTime) || otherStamp.size != stamp.size {
+			changed = append(changed, path)
+		}
+	}
+	for path := range other {
+		if _, ok := s[path]; !ok {
+			changed = append(changed, path)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// OutputChanges describes how the files in an output directory changed between two runs.
+type OutputChanges struct {
+	Added    []string
+	Modified []string
+	Removed  []string
+}
+
+// IsEmpty returns true if no files changed.
+func (c OutputChanges) IsEmpty() bool {
+	return len(c.Added) == 0 && len(c.Modified) == 0 && len(c.Removed) == 0
+}
+
+// HashFiles returns the SHA-256 hash of every file in the directory, keyed by slash-separated relative path.
+// The output marker file is ignored, as it changes on every run.
+func HashFiles(dir string) (map[string]string, error) {
+	files, err := ListFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	hashes := make(map[string]string, len(files))
+	for _, relPath := range files {
+		if relPath == DeployKFOutputMarker {
+			continue
+		}
+		hash, err := hashFile(filepath.Join(dir, filepath.FromSlash(relPath)))
+		if err != nil {
+			return nil, err
+		}
+		hashes[relPath] = hash
+	}
+	return hashes, nil
+}
+
+// DiffFileHashes compares the file hashes from two calls to HashFiles.
+func DiffFileHashes(before map[string]string, after map[string]string) OutputChanges {
+	changes := OutputChanges{}
+	for relPath, hash := range after {
+		beforeHash, ok := before[relPath]
+		if !ok {
+			changes.Added = append(changes.Added, relPath)
+		} else if beforeHash != hash {
+			changes.Modified = append(changes.Modified, relPath)
+		}
+	}
+	for relPath := range before {
+		if _, ok := after[relPath]; !ok {
+			changes.Removed = append(changes.Removed, relPath)
+		}
+	}
+	sort.Strings(changes.Added)
+	sort.Strings(changes.Modified)
+	sort.Strings(changes.Removed)
+	return changes
+}
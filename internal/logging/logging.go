@@ -0,0 +1,193 @@
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the severity of a log message.
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// String returns the name of the level, as used by the `--log-level` flag.
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	}
+	return fmt.Sprintf("level(%d)", int(l))
+}
+
+// ParseLevel parses the name of a level, like "debug" or "warn".
+func ParseLevel(name string) (Level, error) {
+	switch strings.ToLower(name) {
+	case "debug":
+		return LevelDebug, nil
+	case "info":
+		return LevelInfo, nil
+	case "warn", "warning":
+		return LevelWarn, nil
+	case "error":
+		return LevelError, nil
+	}
+	return LevelInfo, fmt.Errorf("unknown log level '%s', must be one of: 'debug', 'info', 'warn', 'error'", name)
+}
+
+// Formats for log messages.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Logger writes leveled log messages to a writer, as either text or JSON lines.
+//
+// In the text format, messages are written like "WARNING: message key=value",
+// where info messages have no prefix, so that they read like normal command output.
+// In the JSON format, each message is written as an object with "time", "level" and "msg" keys, plus any fields.
+type Logger struct {
+	out    io.Writer
+	level  Level
+	format string
+	fields []field
+	mu     *sync.Mutex
+}
+
+type field struct {
+	key   string
+	value interface{}
+}
+
+// New creates a Logger which writes messages at or above the level to out.
+func New(out io.Writer, level Level, format string) (*Logger, error) {
+	if format != FormatText && format != FormatJSON {
+		return nil, fmt.Errorf("unknown log format '%s', must be one of: 'text', 'json'", format)
+	}
+	return &Logger{out: out, level: level, format: format, mu: &sync.Mutex{}}, nil
+}
+
+// Discard returns a Logger which discards all messages.
+func Discard() *Logger {
+	return &Logger{out: io.Discard, level: LevelError + 1, format: FormatText, mu: &sync.Mutex{}}
+}
+
+// With returns a child Logger which adds the key-value field to every message.
+func (l *Logger) With(key string, value interface{}) *Logger {
+	child := *l
+	child.fields = append(append([]field{}, l.fields...), field{key: key, value: value})
+	return &child
+}
+
+// Enabled returns true if messages at the level would be written.
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.level
+}
+
+// Debugf writes a debug message.
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.log(LevelDebug, format, args...)
+}
+
+// Infof writes an info message.
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.log(LevelInfo, format, args...)
+}
+
+// Warnf writes a warning message.
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.log(LevelWarn, format, args...)
+}
+
+// Errorf writes an error message.
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.log(LevelError, format, args...)
+}
+
+func (l *Logger) log(level Level, format string, args ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	message := fmt.Sprintf(format, args...)
+
+	var line string
+	if l.format == FormatJSON {
+		line = l.jsonLine(level, message)
+	} else {
+		line = l.textLine(level, message)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, _ = io.WriteString(l.out, line+"\n")
+}
+
+func (l *Logger) textLine(level Level, message string) string {
+	var b strings.Builder
+	switch level {
+	case LevelDebug:
+		b.WriteString("DEBUG: ")
+	case LevelWarn:
+		b.WriteString("WARNING: ")
+	case LevelError:
+		b.WriteString("ERROR: ")
+	}
+	b.WriteString(message)
+	for _, f := range l.fields {
+		fmt.Fprintf(&b, " %s=%v", f.key, f.value)
+	}
+	return b.String()
+}
+
+func (l *Logger) jsonLine(level Level, message string) string {
+	entry := map[string]interface{}{}
+	for _, f := range l.fields {
+		entry[f.key] = f.value
+	}
+	entry["time"] = time.Now().UTC().Format(time.RFC3339Nano)
+	entry["level"] = level.String()
+	entry["msg"] = message
+
+	// NOTE: `json.Marshal` sorts map keys, but we want "time", "level" and "msg" first
+	keys := make([]string, 0, len(entry))
+	for key := range entry {
+		if key != "time" && key != "level" && key != "msg" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	keys = append([]string{"time", "level", "msg"}, keys...)
+
+	var b strings.Builder
+	b.WriteString("{")
+	for i, key := range keys {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		keyJSON, _ := json.Marshal(key)
+		valueJSON, err := json.Marshal(entry[key])
+		if err != nil {
+			valueJSON, _ = json.Marshal(fmt.Sprint(entry[key]))
+		}
+		b.Write(keyJSON)
+		b.WriteString(":")
+		b.Write(valueJSON)
+	}
+	b.WriteString("}")
+	return b.String()
+}
@@ -0,0 +1,221 @@
+package project
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultConfigFile is the name of the project configuration file which is read by default.
+const DefaultConfigFile = "deploykf.yaml"
+
+// envNameRegex matches valid environment names.
+var envNameRegex = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9_.-]*[a-zA-Z0-9])?$`)
+
+// Config is a project configuration file (`deploykf.yaml`), which declares named environments.
+type Config struct {
+	// Defaults are applied to every environment (values and set overrides come before those of the environment).
+	Defaults Environment `yaml:"defaults"`
+
+	// Environments are the named environments of the project.
+	Environments map[string]Environment `yaml:"environments"`
+
+	// dir is the directory containing the configuration file, which relative paths are resolved against
+	dir string
+}
+
+// Environment is the configuration for generating the manifests of a single environment.
+type Environment struct {
+	SourceVersion string                 `yaml:"source_version"`
+	SourcePath    string                 `yaml:"source_path"`
+	Values        []string               `yaml:"values"`
+	Set           map[string]interface{} `yaml:"set"`
+	OutputDir     string                 `yaml:"output_dir"`
+
+	Validate        *bool    `yaml:"validate"`
+	KubeVersion     string   `yaml:"kube_version"`
+	SchemaLocations []string `yaml:"schema_locations"`
+	ScanSecrets     *bool    `yaml:"scan_secrets"`
+	Strict          *bool    `yaml:"strict"`
+	Keep            []string `yaml:"keep"`
+}
+
+// Load reads and validates a project configuration file.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid project config '%s': %v", path, err)
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	config.dir = filepath.Dir(absPath)
+	return config, nil
+}
+
+// Parse parses and validates a project configuration, unknown fields are not allowed.
+func Parse(data []byte) (*Config, error) {
+	config := &Config{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	err := decoder.Decode(config)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	err = config.validate()
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// validate checks that every environment is complete and consistent.
+func (c *Config) validate() error {
+	if len(c.Environments) == 0 {
+		return fmt.Errorf("no environments are defined")
+	}
+	for _, name := range c.EnvironmentNames() {
+		if !envNameRegex.MatchString(name) {
+			return fmt.Errorf("environment name '%s' is invalid, it must only contain letters, numbers, '-', '_' and '.'", name)
+		}
+		env := c.resolve(c.Environments[name])
+		if env.SourceVersion == "" && env.SourcePath == "" {
+			return fmt.Errorf("environment '%s' must set one of 'source_version' or 'source_path'", name)
+		}
+		if env.SourceVersion != "" && env.SourcePath != "" {
+			return fmt.Errorf("environment '%s' can't set both 'source_version' and 'source_path'", name)
+		}
+		if env.OutputDir == "" {
+			return fmt.Errorf("environment '%s' must set 'output_dir'", name)
+		}
+		for key := range env.Set {
+			if key == "" {
+				return fmt.Errorf("environment '%s' has an empty key in 'set'", name)
+			}
+		}
+	}
+
+	// environments must not write into the same output directory
+	outputDirs := map[string]string{}
+	for _, name := range c.EnvironmentNames() {
+		outputDir := filepath.Clean(c.resolve(c.Environments[name]).OutputDir)
+		if other, ok := outputDirs[outputDir]; ok {
+			return fmt.Errorf("environments '%s' and '%s' have the same 'output_dir'", other, name)
+		}
+		outputDirs[outputDir] = name
+	}
+
+	return nil
+}
+
+// EnvironmentNames returns the sorted names of all environments.
+func (c *Config) EnvironmentNames() []string {
+	names := make([]string, 0, len(c.Environments))
+	for name := range c.Environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Environment returns the named environment, with the defaults applied and relative paths resolved
+// against the directory of the configuration file.
+func (c *Config) Environment(name string) (*Environment, error) {
+	env, ok := c.Environments[name]
+	if !ok {
+		return nil, fmt.Errorf("environment '%s' is not defined, must be one of: %v", name, c.EnvironmentNames())
+	}
+	resolved := c.resolve(env)
+
+	resolved.SourcePath = c.resolvePath(resolved.SourcePath)
+	resolved.OutputDir = c.resolvePath(resolved.OutputDir)
+	for i, valuesRef := range resolved.Values {
+		resolved.Values[i] = c.resolveValuesRef(valuesRef)
+	}
+	for i, location := range resolved.SchemaLocations {
+		resolved.SchemaLocations[i] = c.resolvePath(location)
+	}
+
+	return &resolved, nil
+}
+
+// resolve applies the defaults to an environment.
+func (c *Config) resolve(env Environment) Environment {
+	d := c.Defaults
+	resolved := Environment{
+		SourceVersion:   firstNonEmpty(env.SourceVersion, d.SourceVersion),
+		SourcePath:      firstNonEmpty(env.SourcePath, d.SourcePath),
+		Values:          append(append([]string{}, d.Values...), env.Values...),
+		Set:             map[string]interface{}{},
+		OutputDir:       firstNonEmpty(env.OutputDir, d.OutputDir),
+		Validate:        firstNonNil(env.Validate, d.Validate),
+		KubeVersion:     firstNonEmpty(env.KubeVersion, d.KubeVersion),
+		SchemaLocations: append(append([]string{}, d.SchemaLocations...), env.SchemaLocations...),
+		ScanSecrets:     firstNonNil(env.ScanSecrets, d.ScanSecrets),
+		Strict:          firstNonNil(env.Strict, d.Strict),
+		Keep:            append(append([]string{}, d.Keep...), env.Keep...),
+	}
+
+	// an environment which sets its own source replaces both source fields of the defaults
+	if env.SourceVersion != "" || env.SourcePath != "" {
+		resolved.SourceVersion = env.SourceVersion
+		resolved.SourcePath = env.SourcePath
+	}
+
+	for key, value := range d.Set {
+		resolved.Set[key] = value
+	}
+	for key, value := range env.Set {
+		resolved.Set[key] = value
+	}
+	return resolved
+}
+
+// resolvePath makes a relative path relative to the directory of the configuration file.
+func (c *Config) resolvePath(path string) string {
+	if path == "" || filepath.IsAbs(path) || c.dir == "" {
+		return path
+	}
+	return filepath.Join(c.dir, path)
+}
+
+// resolveValuesRef resolves a values reference like `resolvePath`, unless it is not a local path
+// (stdin as "-", an environment variable like "env:NAME", or a URL like "https://...").
+func (c *Config) resolveValuesRef(ref string) string {
+	if ref == "-" || strings.HasPrefix(ref, "env:") || strings.Contains(ref, "://") {
+		return ref
+	}
+	return c.resolvePath(ref)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+func firstNonNil(values ...*bool) *bool {
+	for _, value := range values {
+		if value != nil {
+			return value
+		}
+	}
+	return nil
+}
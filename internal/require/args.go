@@ -1,15 +1,16 @@
 package require
 
 import (
-	"fmt"
-
 	"github.com/spf13/cobra"
+
+	"github.com/deployKF/cli/internal/exitcode"
 )
 
 // NoArgs returns an error if any args are included.
 func NoArgs(cmd *cobra.Command, args []string) error {
 	if len(args) > 0 {
-		return fmt.Errorf(
+		return exitcode.New(
+			exitcode.KindInvalidFlags,
 			"%q accepts no arguments\n\nUsage:  %s",
 			cmd.CommandPath(),
 			cmd.UseLine(),
@@ -17,3 +18,20 @@ func NoArgs(cmd *cobra.Command, args []string) error {
 	}
 	return nil
 }
+
+// ExactArgs returns an error if there are not exactly n args.
+func ExactArgs(n int) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if len(args) != n {
+			return exitcode.New(
+				exitcode.KindInvalidFlags,
+				"%q requires exactly %d argument(s), but received %d\n\nUsage:  %s",
+				cmd.CommandPath(),
+				n,
+				len(args),
+				cmd.UseLine(),
+			)
+		}
+		return nil
+	}
+}
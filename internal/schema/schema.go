@@ -0,0 +1,565 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Violation describes a single way in which a value does not satisfy a schema.
+type Violation struct {
+	Field   string `json:"field"`   // the path of the failing field, like "spec.containers[0].image"
+	Message string `json:"message"` // a description of the failure
+}
+
+// Schema is a parsed JSON schema.
+// Only the subset of JSON schema used by Kubernetes OpenAPI and CRD schemas is supported.
+// The following keywords are ignored: "format", "patternProperties", "dependencies", "if"/"then"/"else",
+// "contains", "$ref" to other documents, and "x-kubernetes-validations" (CEL rules).
+type Schema struct {
+	root map[string]interface{}
+}
+
+// Load reads a JSON schema from a file.
+func Load(path string) (*Schema, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Parse parses a JSON schema.
+func Parse(data []byte) (*Schema, error) {
+	var root map[string]interface{}
+	err := json.Unmarshal(data, &root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse schema: %v", err)
+	}
+	return &Schema{root: root}, nil
+}
+
+// FromMap creates a Schema from an already decoded JSON schema.
+func FromMap(root map[string]interface{}) *Schema {
+	return &Schema{root: root}
+}
+
+// Validate validates the value against the schema, and returns all violations.
+func (s *Schema) Validate(value interface{}) []Violation {
+	v := &validator{root: s.root}
+	v.validate(s.root, value, "")
+	return v.violations
+}
+
+type validator struct {
+	root       map[string]interface{}
+	violations []Violation
+}
+
+func (v *validator) fail(field string, format string, args ...interface{}) {
+	v.violations = append(v.violations, Violation{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// validate checks a value against a (sub)schema, recording any violations.
+func (v *validator) validate(schema map[string]interface{}, value interface{}, field string) {
+	schema = v.resolveRef(schema)
+	if schema == nil {
+		return
+	}
+
+	// null values are allowed if the schema says so
+	if value == nil {
+		if nullable, _ := schema["nullable"].(bool); nullable {
+			return
+		}
+	}
+
+	// Kubernetes extensions
+	if intOrString, _ := schema["x-kubernetes-int-or-string"].(bool); intOrString {
+		if !isInteger(value) && !isString(value) {
+			v.fail(field, "expected integer or string, got %s", typeName(value))
+		}
+		return
+	}
+
+	// combinators
+	if allOf, ok := schema["allOf"].([]interface{}); ok {
+		for _, sub := range allOf {
+			if subSchema, ok := sub.(map[string]interface{}); ok {
+				v.validate(subSchema, value, field)
+			}
+		}
+	}
+	if anyOf, ok := schema["anyOf"].([]interface{}); ok {
+		if v.countMatches(anyOf, value) == 0 {
+			v.fail(field, "value does not match any of the allowed schemas")
+		}
+	}
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		if matches := v.countMatches(oneOf, value); matches != 1 {
+			v.fail(field, "value must match exactly one of the allowed schemas, but matched %d", matches)
+		}
+	}
+
+	// type
+	if schemaType, ok := schema["type"]; ok {
+		if !matchesType(schemaType, value) {
+			v.fail(field, "expected %s, got %s", describeType(schemaType), typeName(value))
+			return
+		}
+	}
+
+	// enum
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, allowed := range enum {
+			if valuesEqual(allowed, value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.fail(field, "value %v is not one of the allowed values", value)
+		}
+	}
+	if constValue, ok := schema["const"]; ok {
+		if !valuesEqual(constValue, value) {
+			v.fail(field, "value %v must be %v", value, constValue)
+		}
+	}
+	if not, ok := schema["not"].(map[string]interface{}); ok {
+		if v.countMatches([]interface{}{not}, value) == 1 {
+			v.fail(field, "value must not match the schema")
+		}
+	}
+
+	switch typedValue := value.(type) {
+	case map[string]interface{}:
+		v.validateObject(schema, typedValue, field)
+	case []interface{}:
+		v.validateArray(schema, typedValue, field)
+	case string:
+		v.validateString(schema, typedValue, field)
+	default:
+		if isNumber(value) {
+			v.validateNumber(schema, toFloat(value), field)
+		}
+	}
+}
+
+// validateString checks the length and pattern of a string.
+// NOTE: patterns which are not valid Go regular expressions (like ECMA-262 lookaheads) are not checked
+func (v *validator) validateString(schema map[string]interface{}, value string, field string) {
+	length := float64(utf8.RuneCountInString(value))
+	if minLength, ok := schemaNumber(schema, "minLength"); ok && length < minLength {
+		v.fail(field, "string must have at least %v characters, but has %v", minLength, length)
+	}
+	if maxLength, ok := schemaNumber(schema, "maxLength"); ok && length > maxLength {
+		v.fail(field, "string must have at most %v characters, but has %v", maxLength, length)
+	}
+	if pattern, ok := schema["pattern"].(string); ok {
+		re := compilePattern(pattern)
+		if re != nil && !re.MatchString(value) {
+			v.fail(field, "string %q does not match the pattern %q", value, pattern)
+		}
+	}
+}
+
+// validateNumber checks the range of a number.
+// Both forms of "exclusiveMinimum" and "exclusiveMaximum" are supported: a boolean which modifies
+// "minimum" or "maximum" (like in Kubernetes OpenAPI schemas), or a number (like in newer JSON schemas).
+func (v *validator) validateNumber(schema map[string]interface{}, value float64, field string) {
+	if minimum, ok := schemaNumber(schema, "minimum"); ok {
+		if exclusive, _ := schema["exclusiveMinimum"].(bool); exclusive && value <= minimum {
+			v.fail(field, "value %v must be greater than %v", value, minimum)
+		} else if value < minimum {
+			v.fail(field, "value %v must be at least %v", value, minimum)
+		}
+	}
+	if exclusiveMinimum, ok := schemaNumber(schema, "exclusiveMinimum"); ok && value <= exclusiveMinimum {
+		v.fail(field, "value %v must be greater than %v", value, exclusiveMinimum)
+	}
+	if maximum, ok := schemaNumber(schema, "maximum"); ok {
+		if exclusive, _ := schema["exclusiveMaximum"].(bool); exclusive && value >= maximum {
+			v.fail(field, "value %v must be less than %v", value, maximum)
+		} else if value > maximum {
+			v.fail(field, "value %v must be at most %v", value, maximum)
+		}
+	}
+	if exclusiveMaximum, ok := schemaNumber(schema, "exclusiveMaximum"); ok && value >= exclusiveMaximum {
+		v.fail(field, "value %v must be less than %v", value, exclusiveMaximum)
+	}
+	if multipleOf, ok := schemaNumber(schema, "multipleOf"); ok && multipleOf > 0 {
+		quotient := value / multipleOf
+		if math.Abs(quotient-math.Round(quotient)) > 1e-9 {
+			v.fail(field, "value %v must be a multiple of %v", value, multipleOf)
+		}
+	}
+}
+
+// schemaNumber returns a numeric keyword of a schema (booleans are not numbers)
+func schemaNumber(schema map[string]interface{}, keyword string) (float64, bool) {
+	value, ok := schema[keyword]
+	if !ok || !isNumber(value) {
+		return 0, false
+	}
+	return toFloat(value), true
+}
+
+// patternCache caches the compiled "pattern" of schemas, or nil if a pattern is not a valid Go regular expression
+var patternCache sync.Map
+
+// compilePattern returns the compiled regular expression of a "pattern", or nil if it is not valid
+func compilePattern(pattern string) *regexp.Regexp {
+	if cached, ok := patternCache.Load(pattern); ok {
+		return cached.(*regexp.Regexp)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		re = nil
+	}
+	patternCache.Store(pattern, re)
+	return re
+}
+
+// validateObject checks the properties of an object.
+func (v *validator) validateObject(schema map[string]interface{}, value map[string]interface{}, field string) {
+	properties, _ := schema["properties"].(map[string]interface{})
+
+	// required properties
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, r := range required {
+			name, _ := r.(string)
+			if _, exists := value[name]; !exists {
+				v.fail(joinField(field, name), "required field is missing")
+			}
+		}
+	}
+
+	// the number of properties
+	count := float64(len(value))
+	if minProperties, ok := schemaNumber(schema, "minProperties"); ok && count < minProperties {
+		v.fail(field, "object must have at least %v properties, but has %v", minProperties, count)
+	}
+	if maxProperties, ok := schemaNumber(schema, "maxProperties"); ok && count > maxProperties {
+		v.fail(field, "object must have at most %v properties, but has %v", maxProperties, count)
+	}
+
+	// preserve-unknown-fields disables checks for additional properties
+	preserveUnknown, _ := schema["x-kubernetes-preserve-unknown-fields"].(bool)
+
+	// visit the keys in a stable order, so violations are reported consistently
+	keys := make([]string, 0, len(value))
+	for key := range value {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		propField := joinField(field, key)
+		if propSchema, ok := properties[key].(map[string]interface{}); ok {
+			v.validate(propSchema, value[key], propField)
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional && !preserveUnknown {
+				v.fail(propField, "unknown field")
+			}
+		case map[string]interface{}:
+			v.validate(additional, value[key], propField)
+		}
+	}
+}
+
+// validateArray checks the items of an array.
+func (v *validator) validateArray(schema map[string]interface{}, value []interface{}, field string) {
+	count := float64(len(value))
+	if minItems, ok := schemaNumber(schema, "minItems"); ok && count < minItems {
+		v.fail(field, "array must have at least %v items, but has %v", minItems, count)
+	}
+	if maxItems, ok := schemaNumber(schema, "maxItems"); ok && count > maxItems {
+		v.fail(field, "array must have at most %v items, but has %v", maxItems, count)
+	}
+	if uniqueItems, _ := schema["uniqueItems"].(bool); uniqueItems {
+		for i := range value {
+			for j := 0; j < i; j++ {
+				if valuesEqual(value[i], value[j]) {
+					v.fail(field+"["+strconv.Itoa(i)+"]", "array items must be unique, but this is the same as item %d", j)
+					break
+				}
+			}
+		}
+	}
+
+	itemSchema, ok := schema["items"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	for i, item := range value {
+		v.validate(itemSchema, item, field+"["+strconv.Itoa(i)+"]")
+	}
+}
+
+// countMatches returns the number of schemas in the list which the value satisfies.
+func (v *validator) countMatches(schemas []interface{}, value interface{}) int {
+	matches := 0
+	for _, sub := range schemas {
+		subSchema, ok := sub.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		subValidator := &validator{root: v.root}
+		subValidator.validate(subSchema, value, "")
+		if len(subValidator.violations) == 0 {
+			matches++
+		}
+	}
+	return matches
+}
+
+// resolveRef follows a local "$ref" (like "#/definitions/foo") to its schema.
+func (v *validator) resolveRef(schema map[string]interface{}) map[string]interface{} {
+	for i := 0; i < 32; i++ {
+		ref, ok := schema["$ref"].(string)
+		if !ok {
+			return schema
+		}
+		if !strings.HasPrefix(ref, "#/") {
+			// non-local references are not supported, so we skip validation
+			return nil
+		}
+		var current interface{} = v.root
+		for _, part := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+			part = strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")
+			currentMap, ok := current.(map[string]interface{})
+			if !ok {
+				return nil
+			}
+			current = currentMap[part]
+		}
+		schema, ok = current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+	}
+	return nil
+}
+
+// matchesType checks a value against a JSON schema "type" (which may be a string or a list of strings).
+func matchesType(schemaType interface{}, value interface{}) bool {
+	switch t := schemaType.(type) {
+	case string:
+		return matchesSingleType(t, value)
+	case []interface{}:
+		for _, single := range t {
+			if name, ok := single.(string); ok && matchesSingleType(name, value) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func matchesSingleType(schemaType string, value interface{}) bool {
+	switch schemaType {
+	case "object":
+		_, ok := value.(map[string]interface{})
+		return ok
+	case "array":
+		_, ok := value.([]interface{})
+		return ok
+	case "string":
+		return isString(value)
+	case "integer":
+		return isInteger(value)
+	case "number":
+		return isNumber(value)
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "null":
+		return value == nil
+	}
+	return true
+}
+
+func describeType(schemaType interface{}) string {
+	switch t := schemaType.(type) {
+	case string:
+		return t
+	case []interface{}:
+		names := make([]string, 0, len(t))
+		for _, single := range t {
+			names = append(names, fmt.Sprint(single))
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(schemaType)
+}
+
+func isString(value interface{}) bool {
+	_, ok := value.(string)
+	return ok
+}
+
+func isInteger(value interface{}) bool {
+	switch n := value.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return true
+	case float64:
+		return n == math.Trunc(n)
+	case float32:
+		return float64(n) == math.Trunc(float64(n))
+	}
+	return false
+}
+
+func isNumber(value interface{}) bool {
+	switch value.(type) {
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return true
+	}
+	return false
+}
+
+// typeName returns the JSON schema type name of a value.
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	}
+	if isInteger(value) {
+		return "integer"
+	}
+	if isNumber(value) {
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+// valuesEqual compares two decoded values, treating all numbers as float64.
+func valuesEqual(a interface{}, b interface{}) bool {
+	if isNumber(a) && isNumber(b) {
+		return toFloat(a) == toFloat(b)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func toFloat(value interface{}) float64 {
+	f, _ := strconv.ParseFloat(fmt.Sprint(value), 64)
+	return f
+}
+
+// joinField appends a property name to a field path.
+func joinField(field string, name string) string {
+	if field == "" {
+		return name
+	}
+	return field + "." + name
+}
+
+// PropertiesWithFlag returns the dot-separated paths of all properties whose schema sets the named flag to true,
+// for example, `PropertiesWithFlag("x-deploykf-sensitive")`.
+// Properties of arrays and additionalProperties are not traversed.
+func (s *Schema) PropertiesWithFlag(flag string) []string {
+	v := &validator{root: s.root}
+	var paths []string
+	var walk func(schema map[string]interface{}, path string, depth int)
+	walk = func(schema map[string]interface{}, path string, depth int) {
+		schema = v.resolveRef(schema)
+		if schema == nil || depth > 64 {
+			return
+		}
+		if enabled, _ := schema[flag].(bool); enabled && path != "" {
+			paths = append(paths, path)
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		for name, propSchema := range properties {
+			if propMap, ok := propSchema.(map[string]interface{}); ok {
+				walk(propMap, joinField(path, name), depth+1)
+			}
+		}
+	}
+	walk(s.root, "", 0)
+
+	sort.Strings(paths)
+	return paths
+}
+
+// OpenFlag is the extension which marks a property of a values schema as a free-form mapping (like labels),
+// so its keys are never reported as unknown, for example, `"annotations": {"type": "object", "x-deploykf-open": true}`.
+const OpenFlag = "x-deploykf-open"
+
+// Property describes the schema of a single property, see `Schema.Property`.
+type Property struct {
+	Description string        // the "description" of the property (if any)
+	Type        string        // the "type" of the property, like "string" or "object" (if any)
+	Enum        []interface{} // the allowed values of the property (if any)
+	Default     interface{}   // the "default" of the property (if any)
+	Flags       []string      // the extensions (like "x-deploykf-sensitive") which are set to true, in order
+	Open        bool          // true if the property may have any keys (see `OpenFlag`), or allows additionalProperties
+}
+
+// Property returns the schema of the property at a dot-separated path (like "deploykf_core.deploykf_auth"),
+// and false if the schema doesn't define it.
+// Like `PropertiesWithFlag`, properties of arrays and additionalProperties are not traversed.
+func (s *Schema) Property(path string) (Property, bool) {
+	v := &validator{root: s.root}
+	schema := v.resolveRef(s.root)
+	for _, name := range strings.Split(path, ".") {
+		if schema == nil {
+			return Property{}, false
+		}
+		properties, _ := schema["properties"].(map[string]interface{})
+		propSchema, ok := properties[name].(map[string]interface{})
+		if !ok {
+			return Property{}, false
+		}
+		schema = v.resolveRef(propSchema)
+	}
+	if schema == nil {
+		return Property{}, false
+	}
+
+	property := Property{Default: schema["default"]}
+	property.Description, _ = schema["description"].(string)
+	if schemaType, ok := schema["type"]; ok {
+		property.Type = describeType(schemaType)
+	}
+	property.Enum, _ = schema["enum"].([]interface{})
+	property.Open, _ = schema[OpenFlag].(bool)
+	if preserveUnknown, _ := schema["x-kubernetes-preserve-unknown-fields"].(bool); preserveUnknown {
+		property.Open = true
+	}
+	switch additional := schema["additionalProperties"].(type) {
+	case bool:
+		property.Open = property.Open || additional
+	case map[string]interface{}:
+		property.Open = true
+	}
+	for key, value := range schema {
+		if enabled, _ := value.(bool); enabled && strings.HasPrefix(key, "x-") {
+			property.Flags = append(property.Flags, key)
+		}
+	}
+	sort.Strings(property.Flags)
+	return property, true
+}
@@ -0,0 +1,179 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// validateJSON validates a JSON value against a JSON schema, and returns the fields of the violations
+func validateJSON(t *testing.T, schemaJSON string, valueJSON string) []string {
+	t.Helper()
+	s, err := Parse([]byte(schemaJSON))
+	if err != nil {
+		t.Fatalf("Parse(%s) error = %v", schemaJSON, err)
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(valueJSON), &value); err != nil {
+		t.Fatal(err)
+	}
+	fields := []string{}
+	for _, violation := range s.Validate(value) {
+		fields = append(fields, violation.Field)
+	}
+	return fields
+}
+
+func TestValidateKeywords(t *testing.T) {
+	tests := []struct {
+		name    string
+		schema  string
+		value   string
+		wantErr bool
+	}{
+		// type and enum
+		{"type", `{"type": "string"}`, `"a"`, false},
+		{"type mismatch", `{"type": "string"}`, `1`, true},
+		{"integer", `{"type": "integer"}`, `1.5`, true},
+		{"nullable", `{"type": "string", "nullable": true}`, `null`, false},
+		{"int or string", `{"x-kubernetes-int-or-string": true}`, `"50%"`, false},
+		{"enum", `{"enum": ["a", "b"]}`, `"b"`, false},
+		{"enum mismatch", `{"enum": ["a", "b"]}`, `"c"`, true},
+		{"const", `{"const": 1}`, `1`, false},
+		{"const mismatch", `{"const": 1}`, `2`, true},
+
+		// strings
+		{"minLength", `{"type": "string", "minLength": 2}`, `"ab"`, false},
+		{"minLength too short", `{"type": "string", "minLength": 2}`, `"a"`, true},
+		{"minLength counts characters", `{"type": "string", "minLength": 2, "maxLength": 2}`, `"äö"`, false},
+		{"maxLength too long", `{"type": "string", "maxLength": 2}`, `"abc"`, true},
+		{"pattern", `{"type": "string", "pattern": "^[a-z]+$"}`, `"abc"`, false},
+		{"pattern mismatch", `{"type": "string", "pattern": "^[a-z]+$"}`, `"ABC"`, true},
+		{"pattern is not anchored", `{"type": "string", "pattern": "[0-9]"}`, `"a1b"`, false},
+		{"pattern which is not a Go regexp is skipped", `{"type": "string", "pattern": "^(?!x)"}`, `"x"`, false},
+
+		// numbers
+		{"minimum", `{"type": "integer", "minimum": 1}`, `1`, false},
+		{"minimum too small", `{"type": "integer", "minimum": 1}`, `0`, true},
+		{"maximum too large", `{"type": "number", "maximum": 1.5}`, `1.6`, true},
+		{"exclusive minimum boolean", `{"type": "integer", "minimum": 1, "exclusiveMinimum": true}`, `1`, true},
+		{"exclusive maximum boolean", `{"type": "integer", "maximum": 1, "exclusiveMaximum": true}`, `0`, false},
+		{"exclusive minimum number", `{"type": "integer", "exclusiveMinimum": 1}`, `1`, true},
+		{"exclusive maximum number", `{"type": "integer", "exclusiveMaximum": 1}`, `1`, true},
+		{"multipleOf", `{"type": "number", "multipleOf": 0.1}`, `0.3`, false},
+		{"multipleOf mismatch", `{"type": "integer", "multipleOf": 2}`, `3`, true},
+
+		// arrays
+		{"minItems", `{"type": "array", "minItems": 1}`, `[1]`, false},
+		{"minItems too few", `{"type": "array", "minItems": 1}`, `[]`, true},
+		{"maxItems too many", `{"type": "array", "maxItems": 1}`, `[1, 2]`, true},
+		{"uniqueItems", `{"type": "array", "uniqueItems": true}`, `[1, 2]`, false},
+		{"uniqueItems duplicate", `{"type": "array", "uniqueItems": true}`, `[{"a": 1}, {"a": 1}]`, true},
+		{"items", `{"type": "array", "items": {"type": "string"}}`, `["a", 1]`, true},
+
+		// objects
+		{"required", `{"type": "object", "required": ["a"]}`, `{"a": 1}`, false},
+		{"required missing", `{"type": "object", "required": ["a"]}`, `{}`, true},
+		{"minProperties too few", `{"type": "object", "minProperties": 1}`, `{}`, true},
+		{"maxProperties too many", `{"type": "object", "maxProperties": 1}`, `{"a": 1, "b": 2}`, true},
+		{"additionalProperties false", `{"type": "object", "properties": {"a": {}}, "additionalProperties": false}`, `{"b": 1}`, true},
+		{"additionalProperties schema", `{"type": "object", "additionalProperties": {"type": "string"}}`, `{"b": 1}`, true},
+		{"preserve unknown fields", `{"type": "object", "additionalProperties": false, "x-kubernetes-preserve-unknown-fields": true}`, `{"b": 1}`, false},
+
+		// combinations
+		{"allOf", `{"allOf": [{"type": "integer"}, {"minimum": 2}]}`, `1`, true},
+		{"anyOf", `{"anyOf": [{"type": "integer"}, {"type": "string"}]}`, `"a"`, false},
+		{"oneOf matches two", `{"oneOf": [{"type": "integer"}, {"type": "number"}]}`, `1`, true},
+		{"not", `{"not": {"type": "string"}}`, `1`, false},
+		{"not mismatch", `{"not": {"type": "string"}}`, `"a"`, true},
+		{"local ref", `{"definitions": {"port": {"type": "integer", "maximum": 65535}}, "$ref": "#/definitions/port"}`, `70000`, true},
+
+		// unsupported keywords are ignored
+		{"format is ignored", `{"type": "string", "format": "email"}`, `"not an email"`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := validateJSON(t, tt.schema, tt.value)
+			if (len(fields) > 0) != tt.wantErr {
+				t.Errorf("Validate(%s) with schema %s = %q, wantErr %v", tt.value, tt.schema, fields, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateNestedFields(t *testing.T) {
+	schemaJSON := `{
+		"type": "object",
+		"properties": {
+			"spec": {
+				"type": "object",
+				"properties": {
+					"replicas": {"type": "integer", "minimum": 0},
+					"policy": {"type": "string", "enum": ["Always", "Never"]},
+					"ports": {
+						"type": "array",
+						"items": {
+							"type": "object",
+							"properties": {
+								"name": {"type": "string", "maxLength": 15, "pattern": "^[a-z0-9-]+$"}
+							}
+						}
+					}
+				}
+			}
+		}
+	}`
+	valueJSON := `{"spec": {"replicas": -1, "policy": "Sometimes", "ports": [{"name": "http"}, {"name": "HTTPS_PORT_WHICH_IS_LONG"}]}}`
+
+	fields := validateJSON(t, schemaJSON, valueJSON)
+	want := map[string]int{
+		"spec.replicas":      1,
+		"spec.policy":        1,
+		"spec.ports[1].name": 2, // both maxLength and pattern
+	}
+	got := map[string]int{}
+	for _, field := range fields {
+		got[field]++
+	}
+	if len(got) != len(want) {
+		t.Fatalf("violation fields = %q, want %v", fields, want)
+	}
+	for field, count := range want {
+		if got[field] != count {
+			t.Errorf("violations of '%s' = %d, want %d (all: %q)", field, got[field], count, fields)
+		}
+	}
+}
+
+func TestPropertyOpen(t *testing.T) {
+	s, err := Parse([]byte(`{
+		"type": "object",
+		"properties": {
+			"labels": {"type": "object", "x-deploykf-open": true},
+			"annotations": {"type": "object", "additionalProperties": {"type": "string"}},
+			"extra": {"type": "object", "additionalProperties": true},
+			"config": {"type": "object", "x-kubernetes-preserve-unknown-fields": true},
+			"closed": {"type": "object", "additionalProperties": false},
+			"plain": {"type": "object", "properties": {"a": {"type": "string"}}}
+		}
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]bool{
+		"labels":      true,
+		"annotations": true,
+		"extra":       true,
+		"config":      true,
+		"closed":      false,
+		"plain":       false,
+		"plain.a":     false,
+	} {
+		property, ok := s.Property(path)
+		if !ok {
+			t.Fatalf("Property(%q) not found", path)
+		}
+		if property.Open != want {
+			t.Errorf("Property(%q).Open = %v, want %v", path, property.Open, want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package sops
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// CommandEnv is the environment variable which may set the path of the `sops` command (the default is "sops").
+const CommandEnv = "DEPLOYKF_SOPS_COMMAND"
+
+// IsEncrypted returns true if parsed YAML values are a SOPS-encrypted document,
+// which has a top-level 'sops' mapping containing (at least) the 'mac' and 'version' of the encryption.
+func IsEncrypted(values map[string]interface{}) bool {
+	metadata, ok := values["sops"].(map[string]interface{})
+	if !ok {
+		return false
+	}
+	_, hasMac := metadata["mac"]
+	_, hasVersion := metadata["version"]
+	return hasMac && hasVersion
+}
+
+// LookCommand returns the path of the `sops` command, or an error explaining how to install it.
+func LookCommand() (string, error) {
+	command := os.Getenv(CommandEnv)
+	if command == "" {
+		command = "sops"
+	}
+	commandPath, err := exec.LookPath(command)
+	if err != nil {
+		return "", fmt.Errorf("the '%s' command was not found (install it from https://github.com/getsops/sops, or set %s)", command, CommandEnv)
+	}
+	return commandPath, nil
+}
+
+// Decrypt decrypts a SOPS-encrypted YAML document with the `sops` command, which finds the age and PGP keys
+// that are available locally (for example, from 'SOPS_AGE_KEY_FILE' or the GPG keyring).
+// If path is not empty, `sops` reads the document from that file, otherwise data is passed on stdin
+// (which is not supported on Windows, as `sops` reads stdin through '/dev/stdin').
+// The plaintext is only ever returned in memory (the `sops` command writes it to our pipe).
+func Decrypt(ctx context.Context, path string, data []byte) ([]byte, error) {
+	commandPath, err := LookCommand()
+	if err != nil {
+		return nil, err
+	}
+
+	input := path
+	if input == "" {
+		if runtime.GOOS == "windows" {
+			return nil, fmt.Errorf("SOPS-encrypted values must be read from a local file on Windows (not stdin, an environment variable or a URL), as 'sops' can only read stdin through '/dev/stdin'")
+		}
+		input = "/dev/stdin"
+	}
+	cmd := exec.CommandContext(ctx, commandPath, "--decrypt", "--input-type", "yaml", "--output-type", "yaml", input)
+	if path == "" {
+		cmd.Stdin = bytes.NewReader(data)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && stderr.Len() > 0 {
+			return nil, fmt.Errorf("failed to decrypt with SOPS: %s", strings.TrimSpace(stderr.String()))
+		}
+		return nil, fmt.Errorf("failed to decrypt with SOPS: %v", err)
+	}
+	return stdout.Bytes(), nil
+}
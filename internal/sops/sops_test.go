@@ -0,0 +1,108 @@
+package sops
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fakeSops is a `sops` command which "decrypts" values by removing the 'sops' metadata and the 'ENC[...]' markers,
+// and fails like `sops` does when the document contains "BADKEY"
+const fakeSops = `#!/bin/sh
+for last; do :; done
+data=$(cat "$last")
+if echo "$data" | grep -q BADKEY; then
+  echo "Failed to get the data key required to decrypt the SOPS file." >&2
+  exit 128
+fi
+echo "$data" | sed -e '/^sops:/,$d' -e 's/ENC\[\([^]]*\)\]/\1/g'
+`
+
+const encryptedValues = "password: ENC[hunter2]\nsops:\n  mac: abc\n  version: 3.7.3\n"
+
+// installFakeSops puts the fake `sops` command first on the PATH
+func installFakeSops(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake sops command is a shell script")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "sops"), []byte(fakeSops), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+	t.Setenv(CommandEnv, "")
+}
+
+func TestDecrypt(t *testing.T) {
+	installFakeSops(t)
+	valuesPath := filepath.Join(t.TempDir(), "secrets.enc.yaml")
+	if err := os.WriteFile(valuesPath, []byte(encryptedValues), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path string
+		data []byte
+	}{
+		{"from file", valuesPath, nil},
+		{"from stdin", "", []byte(encryptedValues)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plaintext, err := Decrypt(context.Background(), tt.path, tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(plaintext) != "password: hunter2\n" {
+				t.Errorf("Decrypt() = %q, want %q", plaintext, "password: hunter2\n")
+			}
+		})
+	}
+}
+
+func TestDecryptFailure(t *testing.T) {
+	installFakeSops(t)
+
+	_, err := Decrypt(context.Background(), "", []byte("password: ENC[BADKEY]\n"))
+	if err == nil {
+		t.Fatal("Decrypt() error = nil, want an error")
+	}
+	// the error of the `sops` command is included
+	if !strings.Contains(err.Error(), "failed to decrypt with SOPS: Failed to get the data key") {
+		t.Errorf("Decrypt() error = %q, want the error of the sops command", err)
+	}
+}
+
+func TestDecryptCommandNotFound(t *testing.T) {
+	t.Setenv(CommandEnv, filepath.Join(t.TempDir(), "missing-sops"))
+
+	_, err := Decrypt(context.Background(), "", []byte(encryptedValues))
+	if err == nil || !strings.Contains(err.Error(), CommandEnv) {
+		t.Errorf("Decrypt() error = %v, want an error explaining how to install sops", err)
+	}
+}
+
+func TestIsEncrypted(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]interface{}
+		want   bool
+	}{
+		{"encrypted", map[string]interface{}{"sops": map[string]interface{}{"mac": "x", "version": "3.7.3"}}, true},
+		{"no version", map[string]interface{}{"sops": map[string]interface{}{"mac": "x"}}, false},
+		{"sops is a value", map[string]interface{}{"sops": "enabled"}, false},
+		{"plain values", map[string]interface{}{"password": "x"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsEncrypted(tt.values); got != tt.want {
+				t.Errorf("IsEncrypted() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package values
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Explanation describes a key of a values file (like `default_values.yaml`), from its comments and value.
+type Explanation struct {
+	KeyPath     string           // the dot-separated path of the key
+	Description string           // the comments of the key (without the '#' characters)
+	Type        string           // the type of the value, like "map", "list", "string" or "boolean"
+	Default     string           // the value as YAML (empty for maps, which are described by their Children)
+	Children    []ExplainedChild // the keys of a map value, in order
+}
+
+// ExplainedChild describes a key of a map, see `Explanation.Children`.
+type ExplainedChild struct {
+	Name        string // the name of the key
+	Type        string // the type of the value
+	Description string // the first line of the comments of the key (if any)
+}
+
+// MissingKeyError is returned by Explain for keys which don't exist.
+type MissingKeyError struct {
+	KeyPath    string   // the dot-separated path which doesn't exist
+	Parent     string   // the longest parent of KeyPath which does exist ("" for the root)
+	ParentKeys []string // the keys of Parent (if it is a map)
+}
+
+func (e *MissingKeyError) Error() string {
+	parent := "the root"
+	if e.Parent != "" {
+		parent = fmt.Sprintf("'%s'", e.Parent)
+	}
+	if len(e.ParentKeys) == 0 {
+		return fmt.Sprintf("the key '%s' does not exist, and %s has no keys", e.KeyPath, parent)
+	}
+	return fmt.Sprintf("the key '%s' does not exist, %s has the keys: %s", e.KeyPath, parent, strings.Join(e.ParentKeys, ", "))
+}
+
+// Explain describes the key at a dot-separated path (like "deploykf_core.deploykf_auth.dex") of a YAML values file.
+// If the key doesn't exist, a *MissingKeyError is returned.
+func Explain(data []byte, keyPath string) (*Explanation, error) {
+	var root yaml.Node
+	err := yaml.Unmarshal(data, &root)
+	if err != nil {
+		return nil, formatYAMLError(err)
+	}
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("the root must be a mapping of keys to values")
+	}
+	document := root.Content[0]
+
+	keyNode, valueNode := findKey(document, keyPath)
+	if valueNode == nil {
+		return nil, missingKeyError(document, keyPath)
+	}
+
+	explanation := &Explanation{
+		KeyPath:     keyPath,
+		Description: keyDescription(keyNode, valueNode),
+		Type:        nodeTypeName(valueNode),
+	}
+	// NOTE: the first key of a document may have its comments attached to the document
+	if explanation.Description == "" && len(document.Content) > 0 && document.Content[0] == keyNode {
+		explanation.Description = cleanComment(root.HeadComment)
+	}
+
+	if valueNode.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(valueNode.Content); i += 2 {
+			childKey, childValue := valueNode.Content[i], valueNode.Content[i+1]
+			if childValue.Kind == yaml.AliasNode && childValue.Alias != nil {
+				childValue = childValue.Alias
+			}
+			description := keyDescription(childKey, childValue)
+			if index := strings.Index(description, "\n"); index != -1 {
+				description = description[:index]
+			}
+			explanation.Children = append(explanation.Children, ExplainedChild{
+				Name:        childKey.Value,
+				Type:        nodeTypeName(childValue),
+				Description: description,
+			})
+		}
+	} else {
+		explanation.Default, err = marshalWithoutComments(valueNode)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return explanation, nil
+}
+
+// missingKeyError returns the error for a key path which doesn't exist, with the keys of its longest existing parent
+func missingKeyError(document *yaml.Node, keyPath string) *MissingKeyError {
+	keys := strings.Split(keyPath, ".")
+	parent := ""
+	parentNode := document
+	for i := 1; i < len(keys); i++ {
+		candidate := strings.Join(keys[:i], ".")
+		node := findNode(document, candidate)
+		if node == nil {
+			break
+		}
+		parent, parentNode = candidate, node
+	}
+
+	err := &MissingKeyError{KeyPath: keyPath, Parent: parent}
+	if parentNode.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(parentNode.Content); i += 2 {
+			err.ParentKeys = append(err.ParentKeys, parentNode.Content[i].Value)
+		}
+		sort.Strings(err.ParentKeys)
+	}
+	return err
+}
+
+// keyDescription returns the comments of a key, which may be above it or at the end of its line
+func keyDescription(keyNode *yaml.Node, valueNode *yaml.Node) string {
+	var lines []string
+	for _, comment := range []string{keyNode.HeadComment, keyNode.LineComment, valueNode.LineComment} {
+		if cleaned := cleanComment(comment); cleaned != "" {
+			lines = append(lines, cleaned)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// cleanComment removes the '#' characters from a comment, and removes lines without any text (like "## ------")
+func cleanComment(comment string) string {
+	var lines []string
+	for _, line := range strings.Split(comment, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "#"))
+		if strings.IndexFunc(line, func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) == -1 {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// nodeTypeName returns the type of a YAML value, like "map", "list", "string" or "boolean"
+func nodeTypeName(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "map"
+	case yaml.SequenceNode:
+		return "list"
+	case yaml.ScalarNode:
+		switch node.ShortTag() {
+		case "!!bool":
+			return "boolean"
+		case "!!int":
+			return "integer"
+		case "!!float":
+			return "number"
+		case "!!null":
+			return "null"
+		}
+		return "string"
+	}
+	return "unknown"
+}
+
+// marshalWithoutComments encodes a YAML value, without any of its comments
+func marshalWithoutComments(node *yaml.Node) (string, error) {
+	var value interface{}
+	err := node.Decode(&value)
+	if err != nil {
+		return "", formatYAMLError(err)
+	}
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	err = encoder.Encode(value)
+	if err != nil {
+		return "", err
+	}
+	err = encoder.Close()
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
@@ -0,0 +1,136 @@
+package values
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// explainTestValues is a values file with comments, which the tests explain keys from
+const explainTestValues = `## the first key, which is attached to the document
+first: 1
+
+base: &shared_value
+  x: 1
+
+## --------------------------------
+## the app settings
+## (over two lines)
+## --------------------------------
+app:
+  # the number of replicas
+  replicas: 2
+  image: "x:1" # the image
+  # the labels
+  # of the pods
+  labels: {}
+  enabled: true
+  ratio: 0.5
+  list: [a, b]
+  empty: null
+  shared: *shared_value
+`
+
+func TestExplain(t *testing.T) {
+	tests := []struct {
+		name    string
+		keyPath string
+		want    *Explanation
+	}{
+		{
+			name:    "a map lists its child keys",
+			keyPath: "app",
+			want: &Explanation{
+				KeyPath:     "app",
+				Description: "the app settings\n(over two lines)",
+				Type:        "map",
+				Children: []ExplainedChild{
+					{Name: "replicas", Type: "integer", Description: "the number of replicas"},
+					{Name: "image", Type: "string", Description: "the image"},
+					{Name: "labels", Type: "map", Description: "the labels"}, // only the first line
+					{Name: "enabled", Type: "boolean"},
+					{Name: "ratio", Type: "number"},
+					{Name: "list", Type: "list"},
+					{Name: "empty", Type: "null"},
+					{Name: "shared", Type: "map"}, // the type of the anchored node
+				},
+			},
+		},
+		{
+			name:    "a nested key with a comment above it",
+			keyPath: "app.labels",
+			want:    &Explanation{KeyPath: "app.labels", Description: "the labels\nof the pods", Type: "map"},
+		},
+		{
+			name:    "a line comment",
+			keyPath: "app.image",
+			want:    &Explanation{KeyPath: "app.image", Description: "the image", Type: "string", Default: "x:1"},
+		},
+		{
+			name:    "a list",
+			keyPath: "app.list",
+			want:    &Explanation{KeyPath: "app.list", Type: "list", Default: "- a\n- b"},
+		},
+		{
+			name:    "the first key of the document",
+			keyPath: "first",
+			want:    &Explanation{KeyPath: "first", Description: "the first key, which is attached to the document", Type: "integer", Default: "1"},
+		},
+		{
+			name:    "a key below an alias",
+			keyPath: "app.shared.x",
+			want:    &Explanation{KeyPath: "app.shared.x", Type: "integer", Default: "1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Explain([]byte(explainTestValues), tt.keyPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Explain(%q) = %+v, want %+v", tt.keyPath, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExplainMissingKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		keyPath string
+		want    *MissingKeyError
+	}{
+		{
+			name:    "a missing top-level key",
+			keyPath: "missing",
+			want:    &MissingKeyError{KeyPath: "missing", ParentKeys: []string{"app", "base", "first"}},
+		},
+		{
+			name:    "a missing nested key",
+			keyPath: "app.missing.deeper",
+			want: &MissingKeyError{
+				KeyPath:    "app.missing.deeper",
+				Parent:     "app",
+				ParentKeys: []string{"empty", "enabled", "image", "labels", "list", "ratio", "replicas", "shared"},
+			},
+		},
+		{
+			name:    "below a scalar",
+			keyPath: "app.replicas.x",
+			want:    &MissingKeyError{KeyPath: "app.replicas.x", Parent: "app.replicas"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Explain([]byte(explainTestValues), tt.keyPath)
+			var missingErr *MissingKeyError
+			if !errors.As(err, &missingErr) {
+				t.Fatalf("Explain(%q) error = %v, want a *MissingKeyError", tt.keyPath, err)
+			}
+			if !reflect.DeepEqual(missingErr, tt.want) {
+				t.Errorf("Explain(%q) error = %+v, want %+v", tt.keyPath, missingErr, tt.want)
+			}
+		})
+	}
+}
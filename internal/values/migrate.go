@@ -0,0 +1,290 @@
+package values
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MigrationsFile is the name of the optional file of values migration rules in the generator source.
+const MigrationsFile = "values_migrations.yaml"
+
+// the types of MigrationRule
+const (
+	MigrationRename        = "rename"         // renames a key (in the same mapping)
+	MigrationMove          = "move"           // moves a key to another path (which is not below the key)
+	MigrationRemove        = "remove"         // removes a key which no longer has an effect
+	MigrationDefaultChange = "default_change" // the default of a key has changed (which may need manual attention)
+)
+
+// Migrations are the rules for updating values files between versions of the generator source.
+//
+// For example, a `values_migrations.yaml` file:
+//
+//	migrations:
+//	  - from: 0.1.3
+//	    to: 0.1.4
+//	    rules:
+//	      - type: rename
+//	        key: deploykf_core.deploykf_auth.dex.staticPasswords
+//	        to: static_passwords
+//	      - type: move
+//	        key: deploykf_dependencies.cert_manager.clusterIssuer
+//	        to: deploykf_dependencies.cert_manager.cluster_issuer
+//	      - type: remove
+//	        key: kubeflow_tools.pipelines.legacy
+//	        note: the legacy UI was removed
+//	      - type: default_change
+//	        key: deploykf_core.deploykf_auth.dex.expiry.idTokens
+//	        old_default: 60m
+//	        new_default: 24h
+type Migrations struct {
+	Migrations []MigrationStep `yaml:"migrations"`
+}
+
+// MigrationStep is the rules for updating values files from one version to the next.
+type MigrationStep struct {
+	From  string          `yaml:"from"`
+	To    string          `yaml:"to"`
+	Rules []MigrationRule `yaml:"rules"`
+}
+
+// MigrationRule is a single change of the values between two versions.
+type MigrationRule struct {
+	Type       string      `yaml:"type"`        // one of the `Migration*` constants
+	Key        string      `yaml:"key"`         // the dot-separated path of the key
+	To         string      `yaml:"to"`          // the new name of the key (for "rename"), or its new path (for "move")
+	OldDefault interface{} `yaml:"old_default"` // the previous default (for "default_change")
+	NewDefault interface{} `yaml:"new_default"` // the new default (for "default_change")
+	Note       string      `yaml:"note"`        // an explanation of the change (if any)
+}
+
+// MigrationChange describes the effect of a MigrationRule on a values file, see `Migrate`.
+type MigrationChange struct {
+	Step    string // the step of the rule, like "0.1.3 -> 0.1.4"
+	Message string // a description of the change
+	Manual  bool   // true if the values file needs manual attention (the change was not applied)
+}
+
+// ParseMigrations parses and validates migration rules, unknown fields are not allowed.
+func ParseMigrations(data []byte) (*Migrations, error) {
+	migrations := &Migrations{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	err := decoder.Decode(migrations)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, formatYAMLError(err)
+	}
+
+	for i, step := range migrations.Migrations {
+		if step.From == "" || step.To == "" {
+			return nil, fmt.Errorf("migrations[%d]: both 'from' and 'to' must be set", i)
+		}
+		for j, rule := range step.Rules {
+			if rule.Key == "" {
+				return nil, fmt.Errorf("migrations[%d].rules[%d]: 'key' must be set", i, j)
+			}
+			switch rule.Type {
+			case MigrationRename:
+				if rule.To == "" || strings.Contains(rule.To, ".") {
+					return nil, fmt.Errorf("migrations[%d].rules[%d]: 'to' must be the new name of the key (without dots)", i, j)
+				}
+			case MigrationMove:
+				if rule.To == "" {
+					return nil, fmt.Errorf("migrations[%d].rules[%d]: 'to' must be the new path of the key", i, j)
+				}
+				// NOTE: a key can't be moved below itself, as its value would be removed with the key
+				if rule.To == rule.Key || strings.HasPrefix(rule.To, rule.Key+".") {
+					return nil, fmt.Errorf("migrations[%d].rules[%d]: can't move '%s' to '%s', which is below itself", i, j, rule.Key, rule.To)
+				}
+			case MigrationRemove, MigrationDefaultChange:
+			default:
+				return nil, fmt.Errorf("migrations[%d].rules[%d]: unknown type '%s', must be one of: %s, %s, %s, %s", i, j, rule.Type, MigrationRename, MigrationMove, MigrationRemove, MigrationDefaultChange)
+			}
+		}
+	}
+	return migrations, nil
+}
+
+// Steps returns the steps which update values from one version to another, in order.
+// Versions may have a "v" prefix (so "v0.1.4" is the same as "0.1.4").
+func (m *Migrations) Steps(from string, to string) ([]MigrationStep, error) {
+	from, to = trimVersion(from), trimVersion(to)
+	var steps []MigrationStep
+	current := from
+	for current != to {
+		found := false
+		for _, step := range m.Migrations {
+			if trimVersion(step.From) == current {
+				steps = append(steps, step)
+				current = trimVersion(step.To)
+				found = true
+				break
+			}
+		}
+		// NOTE: the number of steps is limited, in case the steps have a cycle
+		if !found || len(steps) > len(m.Migrations) {
+			return nil, fmt.Errorf("there are no migrations from version '%s' to '%s' (stopped at '%s')", from, to, current)
+		}
+	}
+	return steps, nil
+}
+
+// trimVersion removes the "v" prefix of a version
+func trimVersion(version string) string {
+	return strings.TrimPrefix(strings.TrimSpace(version), "v")
+}
+
+// Migrate applies the rules of some steps to a YAML values file, keeping its comments.
+// It returns the updated values file, and the changes which were made (or which need manual attention).
+func Migrate(data []byte, steps []MigrationStep) ([]byte, []MigrationChange, error) {
+	var root yaml.Node
+	err := yaml.Unmarshal(data, &root)
+	if err != nil {
+		return nil, nil, formatYAMLError(err)
+	}
+	if len(root.Content) == 0 {
+		// an empty values file has nothing to migrate
+		return data, nil, nil
+	}
+	document := root.Content[0]
+	if document.Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("line %d: the root must be a mapping of keys to values, but found %s", document.Line, nodeKindName(document))
+	}
+
+	var changes []MigrationChange
+	for _, step := range steps {
+		stepName := fmt.Sprintf("%s -> %s", trimVersion(step.From), trimVersion(step.To))
+		for _, rule := range step.Rules {
+			message, manual := applyRule(document, rule)
+			if message == "" {
+				continue
+			}
+			if rule.Note != "" {
+				message += fmt.Sprintf(" (%s)", rule.Note)
+			}
+			changes = append(changes, MigrationChange{Step: stepName, Message: message, Manual: manual})
+		}
+	}
+
+	result, err := marshalDocument(&root, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	return result, changes, nil
+}
+
+// applyRule applies a rule to a mapping node, and describes the change (or "" if the rule had no effect),
+// and if the values need manual attention
+func applyRule(document *yaml.Node, rule MigrationRule) (string, bool) {
+	keyNode, valueNode := findKey(document, rule.Key)
+
+	switch rule.Type {
+	case MigrationRename:
+		if keyNode == nil {
+			return "", false
+		}
+		newPath := joinKeyPath(parentKeyPath(rule.Key), rule.To)
+		if findNode(document, newPath) != nil {
+			return fmt.Sprintf("both '%s' and its new name '%s' are set, merge them into '%s'", rule.Key, newPath, newPath), true
+		}
+		keyNode.Value = rule.To
+		return fmt.Sprintf("renamed '%s' to '%s'", rule.Key, newPath), false
+
+	case MigrationMove:
+		if keyNode == nil {
+			return "", false
+		}
+		if findNode(document, rule.To) != nil {
+			return fmt.Sprintf("both '%s' and its new path '%s' are set, merge them into '%s'", rule.Key, rule.To, rule.To), true
+		}
+		parent := ensureMapping(document, parentKeyPath(rule.To))
+		if parent == nil {
+			return fmt.Sprintf("can't move '%s' to '%s', as a parent of '%s' is not a mapping", rule.Key, rule.To, rule.To), true
+		}
+		// NOTE: the new parent may be an ancestor of the key, so it is kept even if it becomes empty
+		removeKey(document, rule.Key, parentKeyPath(rule.To))
+		newKeyNode := *keyNode
+		newKeyNode.Value = lastKey(rule.To)
+		parent.Content = append(parent.Content, &newKeyNode, valueNode)
+		return fmt.Sprintf("moved '%s' to '%s'", rule.Key, rule.To), false
+
+	case MigrationRemove:
+		if keyNode == nil {
+			return "", false
+		}
+		removeKey(document, rule.Key, "")
+		return fmt.Sprintf("removed '%s', which no longer has an effect", rule.Key), false
+
+	case MigrationDefaultChange:
+		// NOTE: the change only affects values files which don't set the key
+		if keyNode != nil {
+			return "", false
+		}
+		return fmt.Sprintf("the default of '%s' changed from '%v' to '%v', set it to '%v' to keep the previous behavior", rule.Key, rule.OldDefault, rule.NewDefault, rule.OldDefault), true
+	}
+	return "", false
+}
+
+// parentKeyPath returns the parent of a dot-separated key path ("" for top-level keys)
+func parentKeyPath(keyPath string) string {
+	index := strings.LastIndex(keyPath, ".")
+	if index == -1 {
+		return ""
+	}
+	return keyPath[:index]
+}
+
+// lastKey returns the last key of a dot-separated key path
+func lastKey(keyPath string) string {
+	return keyPath[strings.LastIndex(keyPath, ".")+1:]
+}
+
+// ensureMapping returns the mapping node at a dot-separated key path, creating any missing mappings,
+// or nil if a parent is not a mapping
+func ensureMapping(document *yaml.Node, keyPath string) *yaml.Node {
+	current := document
+	if keyPath == "" {
+		return current
+	}
+	for _, key := range strings.Split(keyPath, ".") {
+		_, next := findKey(current, key)
+		if next == nil {
+			next = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			current.Content = append(current.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, next)
+		}
+		if next.Kind != yaml.MappingNode {
+			return nil
+		}
+		current = next
+	}
+	return current
+}
+
+// removeKey removes the key at a dot-separated key path, and any parent mappings which become empty,
+// except for the mapping at keepPath and its parents (keepPath may be "")
+func removeKey(document *yaml.Node, keyPath string, keepPath string) {
+	parentPath := parentKeyPath(keyPath)
+	parent := document
+	if parentPath != "" {
+		parent = findNode(document, parentPath)
+		if parent == nil || parent.Kind != yaml.MappingNode {
+			return
+		}
+	}
+	key := lastKey(keyPath)
+	for i := 0; i+1 < len(parent.Content); i += 2 {
+		if parent.Content[i].Value == key {
+			parent.Content = append(parent.Content[:i], parent.Content[i+2:]...)
+			break
+		}
+	}
+	keepParent := parentPath == keepPath || strings.HasPrefix(keepPath, parentPath+".")
+	if parentPath != "" && len(parent.Content) == 0 && !keepParent {
+		removeKey(document, parentPath, keepPath)
+	}
+}
@@ -0,0 +1,216 @@
+package values
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseMigrations(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"empty", "", ""},
+		{"valid", "migrations:\n  - from: 0.1.3\n    to: 0.1.4\n    rules:\n      - {type: move, key: a.b, to: c.b}\n", ""},
+		{"unknown field", "migrations:\n  - from: 0.1.3\n    to: 0.1.4\n    typo: true\n", "typo"},
+		{"missing version", "migrations:\n  - from: 0.1.3\n", "both 'from' and 'to' must be set"},
+		{"missing key", "migrations:\n  - {from: 1, to: 2, rules: [{type: remove}]}\n", "'key' must be set"},
+		{"unknown type", "migrations:\n  - {from: 1, to: 2, rules: [{type: copy, key: a}]}\n", "unknown type 'copy'"},
+		{"rename with dots", "migrations:\n  - {from: 1, to: 2, rules: [{type: rename, key: a.b, to: c.d}]}\n", "without dots"},
+		{"move without to", "migrations:\n  - {from: 1, to: 2, rules: [{type: move, key: a.b}]}\n", "new path of the key"},
+		{"move below itself", "migrations:\n  - {from: 1, to: 2, rules: [{type: move, key: a.b, to: a.b.c}]}\n", "below itself"},
+		{"move to itself", "migrations:\n  - {from: 1, to: 2, rules: [{type: move, key: a.b, to: a.b}]}\n", "below itself"},
+		{"move to a sibling with a common prefix", "migrations:\n  - {from: 1, to: 2, rules: [{type: move, key: a.b, to: a.bc}]}\n", ""},
+		{"move to a parent", "migrations:\n  - {from: 1, to: 2, rules: [{type: move, key: a.b.c, to: a.c}]}\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseMigrations([]byte(tt.data))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("ParseMigrations() error = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseMigrations() error = %v, want an error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMigrationsSteps(t *testing.T) {
+	migrations := &Migrations{Migrations: []MigrationStep{
+		{From: "0.1.3", To: "0.1.4"},
+		{From: "v0.1.2", To: "v0.1.3"},
+		{From: "0.1.4", To: "0.1.2"}, // a cycle
+	}}
+
+	steps, err := migrations.Steps("v0.1.2", "0.1.4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 2 || steps[0].From != "v0.1.2" || steps[1].From != "0.1.3" {
+		t.Errorf("Steps() = %+v, want the steps from 0.1.2 and then 0.1.3", steps)
+	}
+
+	_, err = migrations.Steps("0.1.2", "0.1.5")
+	if err == nil {
+		t.Errorf("Steps() to a version without migrations error = nil, want an error")
+	}
+}
+
+// migrateTestValues is a values file with comments, which is migrated by the tests
+const migrateTestValues = `# the header comment
+
+## the first key
+first:
+  # a comment on an untouched key
+  untouched: "quoted"   # a line comment
+  old_name: 1
+  list: [a, b]
+
+## the auth settings
+auth:
+  nested:
+    moved: {x: 1}
+    sibling: 2
+  removed: true
+
+## the last key
+last: 'single'
+`
+
+func TestMigrate(t *testing.T) {
+	steps := []MigrationStep{{From: "0.1.3", To: "0.1.4", Rules: []MigrationRule{
+		{Type: MigrationRename, Key: "first.old_name", To: "new_name"},
+		{Type: MigrationMove, Key: "auth.nested.moved", To: "auth.other.moved", Note: "for consistency"},
+		{Type: MigrationRemove, Key: "auth.removed"},
+		{Type: MigrationRemove, Key: "not.set"},
+		{Type: MigrationDefaultChange, Key: "auth.expiry", OldDefault: "1h", NewDefault: "24h"},
+		{Type: MigrationDefaultChange, Key: "last", OldDefault: "a", NewDefault: "b"},
+	}}}
+
+	result, changes, err := Migrate([]byte(migrateTestValues), steps)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// untouched keys keep their order, comments and quoting
+	//  - note, the values file is re-encoded with a 2-space indent, and only keeps empty lines between top-level keys
+	want := `# the header comment
+
+## the first key
+first:
+  # a comment on an untouched key
+  untouched: "quoted" # a line comment
+  new_name: 1
+  list: [a, b]
+
+## the auth settings
+auth:
+  nested:
+    sibling: 2
+  other:
+    moved: {x: 1}
+
+## the last key
+last: 'single'
+`
+	if string(result) != want {
+		t.Errorf("Migrate() =\n%s\nwant:\n%s", result, want)
+	}
+
+	wantChanges := []MigrationChange{
+		{Step: "0.1.3 -> 0.1.4", Message: "renamed 'first.old_name' to 'first.new_name'"},
+		{Step: "0.1.3 -> 0.1.4", Message: "moved 'auth.nested.moved' to 'auth.other.moved' (for consistency)"},
+		{Step: "0.1.3 -> 0.1.4", Message: "removed 'auth.removed', which no longer has an effect"},
+		{Step: "0.1.3 -> 0.1.4", Message: "the default of 'auth.expiry' changed from '1h' to '24h', set it to '1h' to keep the previous behavior", Manual: true},
+	}
+	if len(changes) != len(wantChanges) {
+		t.Fatalf("Migrate() changes = %+v, want %+v", changes, wantChanges)
+	}
+	for i := range wantChanges {
+		if changes[i] != wantChanges[i] {
+			t.Errorf("Migrate() change %d = %+v, want %+v", i, changes[i], wantChanges[i])
+		}
+	}
+}
+
+func TestMigrateConflicts(t *testing.T) {
+	data := "a:\n  old: 1\n  new: 2\n  moved: 3\nb: scalar\n"
+	steps := []MigrationStep{{From: "1", To: "2", Rules: []MigrationRule{
+		{Type: MigrationRename, Key: "a.old", To: "new"},
+		{Type: MigrationMove, Key: "a.moved", To: "b.moved"},
+	}}}
+
+	result, changes, err := Migrate([]byte(data), steps)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// keys which can't be updated are left as they are
+	if string(result) != "a:\n  old: 1\n  new: 2\n  moved: 3\n\nb: scalar\n" {
+		t.Errorf("Migrate() = %q, want the values unchanged", result)
+	}
+	if len(changes) != 2 || !changes[0].Manual || !changes[1].Manual {
+		t.Errorf("Migrate() changes = %+v, want 2 changes which need manual attention", changes)
+	}
+}
+
+func TestMigrateMove(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		to   string
+		want string
+	}{
+		{
+			name: "to the grandparent mapping",
+			data: "a:\n  b:\n    x: 1\nother: 2\n",
+			to:   "a.y",
+			want: "a:\n  y: 1\n\nother: 2\n",
+		},
+		{
+			name: "to the top level",
+			data: "a:\n  b:\n    x: 1\nother: 2\n",
+			to:   "y",
+			want: "other: 2\ny: 1\n",
+		},
+		{
+			name: "to the same parent",
+			data: "a:\n  b:\n    x: 1\n",
+			to:   "a.b.y",
+			want: "a:\n  b:\n    y: 1\n",
+		},
+		{
+			name: "to a new sibling mapping",
+			data: "a:\n  b:\n    x: 1\n",
+			to:   "a.c.x",
+			want: "a:\n  c:\n    x: 1\n",
+		},
+		{
+			name: "to another top-level mapping",
+			data: "a:\n  b:\n    x: 1\n  keep: true\nz:\n  other: 2\n",
+			to:   "z.x",
+			want: "a:\n  keep: true\n\nz:\n  other: 2\n  x: 1\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			steps := []MigrationStep{{From: "1", To: "2", Rules: []MigrationRule{
+				{Type: MigrationMove, Key: "a.b.x", To: tt.to},
+			}}}
+			result, changes, err := Migrate([]byte(tt.data), steps)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(result) != tt.want {
+				t.Errorf("Migrate() = %q, want %q", result, tt.want)
+			}
+			if len(changes) != 1 || changes[0].Manual {
+				t.Errorf("Migrate() changes = %+v, want 1 applied change", changes)
+			}
+		})
+	}
+}
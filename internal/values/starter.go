@@ -0,0 +1,222 @@
+package values
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Starter is a subset of a values file (like `default_values.yaml`), which keeps the comments of the selected keys.
+type Starter struct {
+	document *yaml.Node
+}
+
+// NewStarter selects the dot-separated key paths (like "deploykf_core.deploykf_istio_gateway.gateway.hostname")
+// from a YAML values file, keeping the order and comments of the file. Selecting a mapping selects all of its keys.
+// It returns the key paths which were not found in the file.
+func NewStarter(data []byte, keyPaths []string) (*Starter, []string, error) {
+	var root yaml.Node
+	err := yaml.Unmarshal(data, &root)
+	if err != nil {
+		return nil, nil, formatYAMLError(err)
+	}
+	if len(root.Content) == 0 || root.Content[0].Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("the root must be a mapping of keys to values")
+	}
+
+	// build a tree of the selected keys, where a nil subtree selects everything below a key
+	selected := keyTree{}
+	var missing []string
+	for _, keyPath := range keyPaths {
+		if findNode(root.Content[0], keyPath) == nil {
+			missing = append(missing, keyPath)
+			continue
+		}
+		selected.add(strings.Split(keyPath, "."))
+	}
+
+	document := &yaml.Node{
+		Kind:        yaml.DocumentNode,
+		HeadComment: root.HeadComment,
+		Content:     []*yaml.Node{selectKeys(root.Content[0], selected)},
+	}
+	return &Starter{document: document}, missing, nil
+}
+
+// Lookup returns the scalar value at a selected key path, and whether it is a selected scalar.
+func (s *Starter) Lookup(keyPath string) (string, bool) {
+	node := findNode(s.document.Content[0], keyPath)
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return "", false
+	}
+	return node.Value, true
+}
+
+// SetScalar replaces the scalar value at a selected key path (keeping its comments), the value is parsed as YAML,
+// so "true" sets a boolean. It returns false if the key path is not a selected scalar.
+func (s *Starter) SetScalar(keyPath string, value string) bool {
+	node := findNode(s.document.Content[0], keyPath)
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return false
+	}
+	var parsed yaml.Node
+	err := yaml.Unmarshal([]byte(value), &parsed)
+	if err != nil || len(parsed.Content) == 0 || parsed.Content[0].Kind != yaml.ScalarNode {
+		// values which are not YAML scalars (like "a: b") are set as strings
+		node.Value = value
+		node.Tag = "!!str"
+		node.Style = yaml.DoubleQuotedStyle
+		return true
+	}
+	node.Value = parsed.Content[0].Value
+	node.Tag = parsed.Content[0].Tag
+	node.Style = parsed.Content[0].Style
+	return true
+}
+
+// Marshal encodes the Starter as YAML, after a header comment (if any).
+func (s *Starter) Marshal(header string) ([]byte, error) {
+	return marshalDocument(s.document, header)
+}
+
+// marshalDocument encodes a YAML document node (keeping its comments), after a header comment (if any)
+func marshalDocument(document *yaml.Node, header string) ([]byte, error) {
+	var encoded bytes.Buffer
+	encoder := yaml.NewEncoder(&encoded)
+	encoder.SetIndent(2)
+	err := encoder.Encode(document)
+	if err != nil {
+		return nil, err
+	}
+	err = encoder.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if header != "" {
+		for _, line := range strings.Split(strings.TrimSpace(header), "\n") {
+			buf.WriteString(strings.TrimSpace("# " + line))
+			buf.WriteString("\n")
+		}
+		buf.WriteString("\n")
+	}
+
+	// separate the top-level keys with an empty line (YAML encoding doesn't keep empty lines)
+	//  - note, a top-level line after an indented line starts the next top-level key (or its comments)
+	previousIndented := false
+	for _, line := range strings.SplitAfter(encoded.String(), "\n") {
+		indented := strings.HasPrefix(line, " ")
+		if previousIndented && !indented && line != "" {
+			buf.WriteString("\n")
+		}
+		buf.WriteString(line)
+		previousIndented = indented
+	}
+	return buf.Bytes(), nil
+}
+
+// keyTree is a tree of selected keys, where a nil subtree selects everything below a key
+type keyTree map[string]keyTree
+
+// add selects a key path
+func (t keyTree) add(keys []string) {
+	subtree, exists := t[keys[0]]
+	if exists && subtree == nil {
+		// a parent of this key is already fully selected
+		return
+	}
+	if len(keys) == 1 {
+		t[keys[0]] = nil
+		return
+	}
+	if !exists {
+		subtree = keyTree{}
+		t[keys[0]] = subtree
+	}
+	subtree.add(keys[1:])
+}
+
+// selectKeys returns a copy of a mapping node with only the selected keys
+func selectKeys(mapping *yaml.Node, selected keyTree) *yaml.Node {
+	result := &yaml.Node{
+		Kind:        yaml.MappingNode,
+		Tag:         mapping.Tag,
+		Style:       mapping.Style,
+		HeadComment: mapping.HeadComment,
+		LineComment: mapping.LineComment,
+		FootComment: mapping.FootComment,
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		keyNode, valueNode := mapping.Content[i], mapping.Content[i+1]
+		subtree, ok := selected[keyNode.Value]
+		if !ok {
+			continue
+		}
+		if subtree == nil {
+			result.Content = append(result.Content, copyNode(keyNode), copyNode(valueNode))
+			continue
+		}
+		if valueNode.Kind == yaml.AliasNode && valueNode.Alias != nil {
+			// like findKey, the keys below an alias are the keys of its anchored node
+			valueNode = valueNode.Alias
+		}
+		if valueNode.Kind == yaml.MappingNode {
+			result.Content = append(result.Content, copyNode(keyNode), selectKeys(valueNode, subtree))
+		}
+	}
+	return result
+}
+
+// copyNode returns a deep copy of a node, where aliases are replaced by a copy of their anchored node
+// (as the anchor may not be selected)
+func copyNode(node *yaml.Node) *yaml.Node {
+	if node.Kind == yaml.AliasNode && node.Alias != nil {
+		result := copyNode(node.Alias)
+		result.Anchor = ""
+		result.HeadComment = node.HeadComment
+		result.LineComment = node.LineComment
+		result.FootComment = node.FootComment
+		return result
+	}
+	result := *node
+	result.Content = make([]*yaml.Node, len(node.Content))
+	for i, child := range node.Content {
+		result.Content[i] = copyNode(child)
+	}
+	return &result
+}
+
+// findNode returns the value node at a dot-separated key path in a mapping node, or nil if it does not exist
+func findNode(mapping *yaml.Node, keyPath string) *yaml.Node {
+	_, valueNode := findKey(mapping, keyPath)
+	return valueNode
+}
+
+// findKey returns the key and value nodes at a dot-separated key path in a mapping node, or nils if it does not exist
+func findKey(mapping *yaml.Node, keyPath string) (*yaml.Node, *yaml.Node) {
+	var keyNode *yaml.Node
+	current := mapping
+	for _, key := range strings.Split(keyPath, ".") {
+		if current.Kind != yaml.MappingNode {
+			return nil, nil
+		}
+		var next *yaml.Node
+		for i := 0; i+1 < len(current.Content); i += 2 {
+			if current.Content[i].Value == key {
+				keyNode, next = current.Content[i], current.Content[i+1]
+				break
+			}
+		}
+		if next == nil {
+			return nil, nil
+		}
+		if next.Kind == yaml.AliasNode && next.Alias != nil {
+			next = next.Alias
+		}
+		current = next
+	}
+	return keyNode, current
+}
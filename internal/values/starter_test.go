@@ -0,0 +1,118 @@
+package values
+
+import (
+	"reflect"
+	"testing"
+)
+
+// starterTestValues is a values file with comments and aliases, which the tests select keys from
+const starterTestValues = `## the base settings
+base: &defaults
+  # the replicas
+  replicas: 1
+  image: x
+
+## the app settings
+app:
+  # the settings of the app
+  settings: *defaults
+  enabled: false
+
+## the last key
+last: true
+`
+
+func TestNewStarter(t *testing.T) {
+	tests := []struct {
+		name        string
+		keyPaths    []string
+		want        string
+		wantMissing []string
+	}{
+		{
+			name:     "a nested key",
+			keyPaths: []string{"app.enabled"},
+			want:     "## the app settings\napp:\n  enabled: false\n",
+		},
+		{
+			name:     "a mapping selects all of its keys",
+			keyPaths: []string{"base", "base.image"},
+			want:     "## the base settings\nbase: &defaults\n  # the replicas\n  replicas: 1\n  image: x\n",
+		},
+		{
+			name:     "keeps the order of the file",
+			keyPaths: []string{"last", "app.enabled"},
+			want:     "## the app settings\napp:\n  enabled: false\n\n## the last key\nlast: true\n",
+		},
+		{
+			name:     "an alias is replaced by its anchored node",
+			keyPaths: []string{"app.settings"},
+			want:     "## the app settings\napp:\n  # the settings of the app\n  settings:\n    # the replicas\n    replicas: 1\n    image: x\n",
+		},
+		{
+			name:     "a key below an alias",
+			keyPaths: []string{"app.settings.image"},
+			want:     "## the app settings\napp:\n  # the settings of the app\n  settings:\n    image: x\n",
+		},
+		{
+			name:        "missing keys",
+			keyPaths:    []string{"last", "app.missing", "last.below_a_scalar"},
+			want:        "## the last key\nlast: true\n",
+			wantMissing: []string{"app.missing", "last.below_a_scalar"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			starter, missing, err := NewStarter([]byte(starterTestValues), tt.keyPaths)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(missing, tt.wantMissing) {
+				t.Errorf("NewStarter(%q) missing = %q, want %q", tt.keyPaths, missing, tt.wantMissing)
+			}
+			result, err := starter.Marshal("")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(result) != tt.want {
+				t.Errorf("NewStarter(%q) =\n%s\nwant:\n%s", tt.keyPaths, result, tt.want)
+			}
+		})
+	}
+}
+
+func TestStarterSetScalar(t *testing.T) {
+	starter, _, err := NewStarter([]byte(starterTestValues), []string{"app.enabled", "app.settings.image", "base"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		keyPath string
+		value   string
+		want    bool
+	}{
+		{"app.enabled", "true", true},
+		{"app.settings.image", "a: b", true}, // not a YAML scalar, so it is set as a string
+		{"base", "1", false},                 // a mapping
+		{"last", "false", false},             // not selected
+	}
+	for _, tt := range tests {
+		if got := starter.SetScalar(tt.keyPath, tt.value); got != tt.want {
+			t.Errorf("SetScalar(%q, %q) = %v, want %v", tt.keyPath, tt.value, got, tt.want)
+		}
+	}
+
+	result, err := starter.Marshal("the header")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "# the header\n\n## the base settings\nbase: &defaults\n  # the replicas\n  replicas: 1\n  image: x\n\n" +
+		"## the app settings\napp:\n  # the settings of the app\n  settings:\n    image: \"a: b\"\n  enabled: true\n"
+	if string(result) != want {
+		t.Errorf("Marshal() =\n%s\nwant:\n%s", result, want)
+	}
+	if value, ok := starter.Lookup("app.enabled"); !ok || value != "true" {
+		t.Errorf("Lookup(%q) = %q, %v, want %q, true", "app.enabled", value, ok, "true")
+	}
+}
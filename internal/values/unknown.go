@@ -0,0 +1,134 @@
+package values
+
+import (
+	"sort"
+	"strings"
+)
+
+// UnknownKey is a key of some values which does not exist in the default values, see `UnknownKeys`.
+type UnknownKey struct {
+	KeyPath    string // the dot-separated path of the key
+	Suggestion string // the dot-separated path of the most similar key in the default values (if any)
+}
+
+// KeyLookup describes a key from outside the default values (like a values schema), see `UnknownKeys`.
+// It returns true for known if the key exists, and true for open if the key is a free-form mapping (like labels).
+type KeyLookup func(keyPath string) (known bool, open bool)
+
+// UnknownKeys returns the keys of some values which don't exist in the default values (like `default_values.yaml`),
+// in order of their path. The keys below an unknown key are not returned.
+//   - note, defaults which are not a mapping (like lists and null), or are an empty mapping, may contain any keys
+//   - note, keys which the lookup knows are always allowed, and open keys may contain any keys (lookup may be nil)
+func UnknownKeys(values map[string]interface{}, defaults map[string]interface{}, lookup KeyLookup) []UnknownKey {
+	var unknown []UnknownKey
+	var walk func(values map[string]interface{}, defaults map[string]interface{}, parentPath string)
+	walk = func(values map[string]interface{}, defaults map[string]interface{}, parentPath string) {
+		for key, value := range values {
+			keyPath := joinKeyPath(parentPath, key)
+			known, open := false, false
+			if lookup != nil {
+				known, open = lookup(keyPath)
+			}
+			defaultValue, exists := defaults[key]
+			if !exists {
+				if known {
+					continue
+				}
+				unknownKey := UnknownKey{KeyPath: keyPath}
+				if suggestion := closestKey(key, defaults); suggestion != "" {
+					unknownKey.Suggestion = joinKeyPath(parentPath, suggestion)
+				}
+				unknown = append(unknown, unknownKey)
+				continue
+			}
+			if open {
+				continue
+			}
+			valueMap, valueIsMap := value.(map[string]interface{})
+			defaultMap, defaultIsMap := defaultValue.(map[string]interface{})
+			if valueIsMap && defaultIsMap && len(defaultMap) > 0 {
+				walk(valueMap, defaultMap, keyPath)
+			}
+		}
+	}
+	walk(values, defaults, "")
+
+	sort.Slice(unknown, func(i, j int) bool {
+		return unknown[i].KeyPath < unknown[j].KeyPath
+	})
+	return unknown
+}
+
+// joinKeyPath appends a key to a dot-separated key path
+func joinKeyPath(parentPath string, key string) string {
+	if parentPath == "" {
+		return key
+	}
+	return parentPath + "." + key
+}
+
+// closestKey returns the key of a mapping which is most similar to a key, or "" if none are similar enough
+//   - note, keys are similar if their edit distance is at most a third of their length (and at least 1),
+//     or if they only differ in case, '-' and '_'
+func closestKey(key string, mapping map[string]interface{}) string {
+	normalize := func(s string) string {
+		return strings.ReplaceAll(strings.ToLower(s), "-", "_")
+	}
+
+	best := ""
+	bestDistance := 0
+	for candidate := range mapping {
+		distance := editDistance(normalize(key), normalize(candidate))
+		maxDistance := len(candidate) / 3
+		if maxDistance < 1 {
+			maxDistance = 1
+		}
+		if distance > maxDistance {
+			continue
+		}
+		// NOTE: ties are broken by name, so suggestions are stable
+		if best == "" || distance < bestDistance || (distance == bestDistance && candidate < best) {
+			best, bestDistance = candidate, distance
+		}
+	}
+	return best
+}
+
+// editDistance returns the Damerau-Levenshtein (optimal string alignment) distance between two strings,
+// which is the number of inserted, removed, replaced or swapped characters to change one into the other
+func editDistance(a string, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	// NOTE: we keep the last three rows, as swaps look two rows back
+	previous2 := make([]int, len(rb)+1)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = minInt(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				current[j] = minInt(current[j], previous2[j-2]+1)
+			}
+		}
+		previous2, previous, current = previous, current, previous2
+	}
+	return previous[len(rb)]
+}
+
+// minInt returns the smallest of some integers
+func minInt(first int, rest ...int) int {
+	result := first
+	for _, value := range rest {
+		if value < result {
+			result = value
+		}
+	}
+	return result
+}
@@ -0,0 +1,132 @@
+package values
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"abc", "abc", 0},
+		{"", "abc", 3},
+		{"abc", "", 3},
+		{"replicas", "replica", 1},   // removed
+		{"replicas", "replicass", 1}, // inserted
+		{"replicas", "replicaz", 1},  // replaced
+		{"replicas", "rpelicas", 1},  // swapped
+		{"kitten", "sitting", 3},
+		{"ca", "abc", 3}, // optimal string alignment doesn't edit a substring twice
+		{"über", "uber", 1},
+	}
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestClosestKey(t *testing.T) {
+	mapping := map[string]interface{}{
+		"replicas":        1,
+		"image":           "x",
+		"cluster_issuer":  "y",
+		"clusterDomain":   "z",
+		"node_selector":   nil,
+		"node_selectors2": nil,
+	}
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"replicas", "replicas"},
+		{"replica", "replicas"},
+		{"rpelicas", "replicas"},
+		{"imgae", "image"},
+		{"imag", "image"},
+		{"img", ""}, // two edits of a five character key is too many
+		{"cluster-issuer", "cluster_issuer"},
+		{"CLUSTER_ISSUER", "cluster_issuer"},
+		{"clusterdomain", "clusterDomain"},
+		{"node_selectr", "node_selector"}, // the closest key wins over a similar one
+		{"something_else", ""},
+	}
+	for _, tt := range tests {
+		if got := closestKey(tt.key, mapping); got != tt.want {
+			t.Errorf("closestKey(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestUnknownKeys(t *testing.T) {
+	defaults := map[string]interface{}{
+		"app": map[string]interface{}{
+			"replicas": 1,
+			"image":    "x",
+			"labels":   map[string]interface{}{}, // an empty mapping may contain any keys
+			"annotations": map[string]interface{}{
+				"example.com/owner": "team",
+			},
+			"extra": nil,
+		},
+		"list": []interface{}{"a"},
+	}
+	values := map[string]interface{}{
+		"app": map[string]interface{}{
+			"replicas": 2,
+			"imgae":    "y",
+			"labels":   map[string]interface{}{"team": "a"},
+			"annotations": map[string]interface{}{
+				"example.com/owner":  "me",
+				"example.com/ticket": "123",
+			},
+			"extra":  map[string]interface{}{"any": "thing"},
+			"schema": map[string]interface{}{"key": true},
+		},
+		"list":    []interface{}{"b"},
+		"unknown": map[string]interface{}{"nested": 1},
+	}
+
+	tests := []struct {
+		name   string
+		lookup KeyLookup
+		want   []UnknownKey
+	}{
+		{
+			name: "without lookup",
+			want: []UnknownKey{
+				{KeyPath: "app.annotations.example.com/ticket", Suggestion: "app.annotations.example.com/owner"},
+				{KeyPath: "app.imgae", Suggestion: "app.image"},
+				{KeyPath: "app.schema"},
+				{KeyPath: "unknown"},
+			},
+		},
+		{
+			name: "with known and open keys",
+			lookup: func(keyPath string) (bool, bool) {
+				switch keyPath {
+				case "app.schema":
+					return true, false
+				case "app.annotations":
+					return true, true
+				}
+				return false, false
+			},
+			want: []UnknownKey{
+				{KeyPath: "app.imgae", Suggestion: "app.image"},
+				{KeyPath: "unknown"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := UnknownKeys(values, defaults, tt.lookup)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UnknownKeys() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package values
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ReadFile reads a YAML values file, which must contain a mapping at its root (without duplicate keys).
+// An empty file is treated as an empty mapping.
+func ReadFile(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	values, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse values file '%s': %v", path, err)
+	}
+	return values, nil
+}
+
+// Parse parses YAML values, which must contain a mapping at the root (without duplicate keys).
+// Empty data is treated as an empty mapping.
+// Errors include the line of the problem (if known), like "line 3: mapping key "a" already defined at line 1".
+func Parse(data []byte) (map[string]interface{}, error) {
+	var root yaml.Node
+	err := yaml.Unmarshal(data, &root)
+	if err != nil {
+		return nil, formatYAMLError(err)
+	}
+
+	// an empty document (or one which only contains comments) is an empty mapping
+	if len(root.Content) == 0 {
+		return map[string]interface{}{}, nil
+	}
+	document := root.Content[0]
+	if document.Kind == yaml.ScalarNode && document.Tag == "!!null" {
+		return map[string]interface{}{}, nil
+	}
+	if document.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: the root must be a mapping of keys to values, but found %s", document.Line, nodeKindName(document))
+	}
+
+	// NOTE: decoding a mapping fails if it has duplicate keys
+	values := map[string]interface{}{}
+	err = document.Decode(&values)
+	if err != nil {
+		return nil, formatYAMLError(err)
+	}
+	return values, nil
+}
+
+// formatYAMLError removes the "yaml: " prefixes from a YAML error, and joins multiple errors into a single line
+func formatYAMLError(err error) error {
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		return errors.New(strings.Join(typeErr.Errors, "; "))
+	}
+	return errors.New(strings.TrimPrefix(err.Error(), "yaml: "))
+}
+
+// nodeKindName describes the kind of a YAML node, for error messages
+func nodeKindName(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.SequenceNode:
+		return "a list"
+	case yaml.ScalarNode:
+		return fmt.Sprintf("the value '%s'", node.Value)
+	case yaml.AliasNode:
+		return "an alias"
+	}
+	return "a document"
+}
+
+// Merge deeply merges the src values into the dst values, and returns dst.
+// Values from src take precedence, nested mappings are merged, and all other values (including lists) are replaced.
+func Merge(dst map[string]interface{}, src map[string]interface{}) map[string]interface{} {
+	if dst == nil {
+		dst = map[string]interface{}{}
+	}
+	for key, srcValue := range src {
+		srcMap, srcIsMap := srcValue.(map[string]interface{})
+		dstMap, dstIsMap := dst[key].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			dst[key] = Merge(dstMap, srcMap)
+		} else if srcIsMap {
+			dst[key] = Merge(map[string]interface{}{}, srcMap)
+		} else {
+			dst[key] = srcValue
+		}
+	}
+	return dst
+}
+
+// Lookup returns the value at the dot-separated key path, like "deploykf_core.deploykf_auth.dex".
+func Lookup(values map[string]interface{}, keyPath string) (interface{}, bool) {
+	var current interface{} = values
+	for _, key := range strings.Split(keyPath, ".") {
+		currentMap, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current, ok = currentMap[key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// SetPath sets the value at the dot-separated key path, creating (or replacing) intermediate mappings as needed.
+func SetPath(values map[string]interface{}, keyPath string, value interface{}) {
+	keys := strings.Split(keyPath, ".")
+	current := values
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := current[key].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			current[key] = next
+		}
+		current = next
+	}
+	current[keys[len(keys)-1]] = value
+}
+
+// ParseSet parses a "key.path=value" override, where the value is parsed as a YAML scalar (or flow collection),
+// so "a.b=true" sets a boolean, and "a.b='true'" sets a string.
+func ParseSet(set string) (string, interface{}, error) {
+	keyPath, rawValue, found := strings.Cut(set, "=")
+	if !found || keyPath == "" {
+		return "", nil, fmt.Errorf("invalid set override '%s', must be like 'key.path=value'", set)
+	}
+	for _, key := range strings.Split(keyPath, ".") {
+		if key == "" {
+			return "", nil, fmt.Errorf("invalid set override '%s', the key path '%s' has an empty key", set, keyPath)
+		}
+	}
+
+	var value interface{}
+	err := yaml.Unmarshal([]byte(rawValue), &value)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid set override '%s': %v", set, err)
+	}
+	return keyPath, value, nil
+}
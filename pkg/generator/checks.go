@@ -0,0 +1,140 @@
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/deployKF/cli/internal/exitcode"
+	"github.com/deployKF/cli/internal/generate"
+	"github.com/deployKF/cli/internal/schema"
+	"github.com/deployKF/cli/internal/values"
+)
+
+// validate the generated Kubernetes objects against their schemas, and log (or write) the results
+func (r *run) validateKubernetesObjects(stagingPath string, sourceSchemasPath string) error {
+	// the schemas from the generator source are searched after the user-provided locations
+	schemaLocations := append([]string{}, r.opts.SchemaLocations...)
+	sourceSchemasExist, err := generate.DirectoryExists(sourceSchemasPath)
+	if err != nil {
+		return err
+	}
+	if sourceSchemasExist {
+		schemaLocations = append(schemaLocations, sourceSchemasPath)
+	}
+	if len(schemaLocations) == 0 {
+		return exitcode.New(exitcode.KindInvalidFlags, "--validate needs at least one --schema-location, as the generator source has no 'schemas' folder")
+	}
+
+	r.log.Debugf("schema locations for Kubernetes version '%s': %s", r.opts.KubeVersion, strings.Join(schemaLocations, ", "))
+	schemaLoader := generate.NewKubeSchemaLoader(r.opts.KubeVersion, schemaLocations)
+	result, err := generate.ValidateKubernetesObjects(stagingPath, schemaLoader)
+	if err != nil {
+		return err
+	}
+	r.report.Validation = result
+
+	// if no object had a schema, the schema locations are probably wrong, so warn once (rather than for every object)
+	missingSchemas := result.MissingSchemas
+	if len(missingSchemas) > 0 && result.ValidObjects+result.InvalidObjects == 0 {
+		missingSchemas = nil
+		r.warn(CodeMissingSchema, "no schemas were found for any of the %d generated Kubernetes objects, check the --schema-location directories and --kube-version: %s", result.SkippedObjects, strings.Join(schemaLocations, ", "))
+	}
+
+	// with a `ValidationOutput`, the results are only written as a whole (and recorded in the report)
+	if r.opts.ValidationOutput != nil {
+		for _, objErr := range result.Errors {
+			r.report.AddError(CodeSchemaViolation, formatKubeObjectError(objErr))
+		}
+		for _, ref := range missingSchemas {
+			r.report.AddWarning(CodeMissingSchema, formatMissingSchema(ref))
+		}
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(r.opts.ValidationOutput, string(data))
+	} else {
+		for _, objErr := range result.Errors {
+			r.fail(CodeSchemaViolation, "%s", formatKubeObjectError(objErr))
+		}
+		for _, ref := range missingSchemas {
+			r.warn(CodeMissingSchema, "%s", formatMissingSchema(ref))
+		}
+		r.log.Infof("Validated Kubernetes objects: %d valid, %d invalid, %d skipped", result.ValidObjects, result.InvalidObjects, result.SkippedObjects)
+	}
+
+	if result.InvalidObjects > 0 {
+		return exitcode.New(exitcode.KindCheckFailed, "found %d generated Kubernetes objects which do not match their schema", result.InvalidObjects)
+	}
+	return nil
+}
+
+// formatKubeObjectError formats a schema violation like "file: kind/namespace/name: field: message"
+func formatKubeObjectError(objErr generate.KubeObjectError) string {
+	object := objErr.Kind + "/" + objErr.Name
+	if objErr.Namespace != "" {
+		object = objErr.Kind + "/" + objErr.Namespace + "/" + objErr.Name
+	}
+	if objErr.Field != "" {
+		return fmt.Sprintf("invalid object: %s: %s: %s: %s", objErr.File, object, objErr.Field, objErr.Message)
+	}
+	return fmt.Sprintf("invalid object: %s: %s: %s", objErr.File, object, objErr.Message)
+}
+
+// formatMissingSchema formats an object without a schema
+func formatMissingSchema(ref generate.KubeObjectRef) string {
+	return fmt.Sprintf("no schema found for '%s %s' in: %s", ref.APIVersion, ref.Kind, ref.File)
+}
+
+// scan the generated manifests for possible secret leaks, and log the findings
+func (r *run) scanForSecrets(stagingPath string, sourceDir string, defaultValuesPath string, userValues map[string]interface{}) error {
+	// find the values keys which are marked as sensitive in the values schema (if any)
+	var sensitiveValues []generate.SensitiveValue
+	valuesSchemaPath := filepath.Join(sourceDir, generate.ValuesSchemaFile)
+	valuesSchemaExists, err := generate.FileExists(valuesSchemaPath)
+	if err != nil {
+		return err
+	}
+	if valuesSchemaExists {
+		valuesSchema, err := schema.Load(valuesSchemaPath)
+		if err != nil {
+			return err
+		}
+
+		// merge the user-provided values over the `default_values.yaml`, the same way gomplate does
+		mergedValues, err := values.ReadFile(defaultValuesPath)
+		if err != nil {
+			return exitcode.Wrap(exitcode.KindUnsupportedSource, err)
+		}
+		mergedValues = values.Merge(mergedValues, userValues)
+
+		sensitiveKeys := valuesSchema.PropertiesWithFlag(generate.SensitiveSchemaFlag)
+		r.log.Debugf("found %d sensitive keys in values schema: %s", len(sensitiveKeys), valuesSchemaPath)
+		sensitiveValues = generate.FindSensitiveValues(sensitiveKeys, func(key string) (interface{}, bool) {
+			return values.Lookup(mergedValues, key)
+		})
+	}
+
+	findings, err := generate.ScanForSecrets(stagingPath, sensitiveValues)
+	if err != nil {
+		return err
+	}
+	r.report.SecretFindings = findings
+	if len(findings) == 0 {
+		return nil
+	}
+
+	for _, finding := range findings {
+		if r.opts.AllowSecrets {
+			r.warn(CodeSecretLeak, "possible secret leak: %s", finding)
+		} else {
+			r.fail(CodeSecretLeak, "possible secret leak: %s", finding)
+		}
+	}
+	if !r.opts.AllowSecrets {
+		return exitcode.New(exitcode.KindCheckFailed, "found %d possible secret leaks in the generated manifests (use --allow-secrets to acknowledge)", len(findings))
+	}
+	return nil
+}
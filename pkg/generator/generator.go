@@ -0,0 +1,539 @@
+// Package generator renders the deployKF Kubernetes manifests from a generator source and configuration values.
+// It is the library behind the `deploykf generate` command, so that other tools can generate manifests in-process.
+//
+// For example:
+//
+//	g, err := generator.New(generator.Options{
+//		SourceVersion: "0.1.4",
+//		ValuesFiles:   []string{"./values.yaml"},
+//		Set:           map[string]interface{}{"deploykf_core.deploykf_auth.dex.expiry.idTokens": "24h"},
+//		OutputDir:     "./GENERATOR_OUTPUT",
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	report, err := g.Generate(ctx)
+package generator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/deployKF/cli/internal/exitcode"
+	"github.com/deployKF/cli/internal/generate"
+	"github.com/deployKF/cli/internal/logging"
+	"github.com/deployKF/cli/internal/version"
+)
+
+// DefaultKubeVersion is the Kubernetes version of the schemas used by Validate, if Options.KubeVersion is empty.
+const DefaultKubeVersion = "master"
+
+// Logger receives the log messages of a Generator.
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// Options configure a Generator.
+type Options struct {
+	// SourceVersion is a version tag from the 'deployKF/deployKF' GitHub repository, which is downloaded (and cached).
+	SourceVersion string
+
+	// SourcePath is a local directory or '.zip' file containing a generator source.
+	SourcePath string
+
+	// Source is a generator source which was already opened with OpenSource (for example, to share it between many
+	// Generators), it is not closed by the Generator.
+	// NOTE: exactly one of SourceVersion, SourcePath or Source must be set
+	Source *Source
+
+	// ValuesFiles are YAML files containing configuration values, where later files take precedence.
+	ValuesFiles []string
+
+	// Values are configuration values from files, URLs or memory (see ValuesFromRef, ValuesFromYAML and ValuesFromMap),
+	// which take precedence over the ValuesFiles (and later values take precedence).
+	// NOTE: in-memory values are never written to disk
+	Values []Values
+
+	// Set are overrides for single values by their dot-separated key (like "a.b.c"), which take precedence over all other values.
+	Set map[string]interface{}
+
+	// OutputDir is the directory in which to generate the manifests.
+	// If it is non-empty, it must contain a '.deploykf_output' marker file, and is cleaned before the manifests are written.
+	OutputDir string
+
+	// OutputArchive is a '.tar.gz', '.tgz' or '.zip' file in which to package the generated manifests.
+	OutputArchive string
+
+	// OutputStream receives the generated manifests as a single multi-document YAML stream.
+	// NOTE: exactly one of OutputDir, OutputArchive or OutputStream must be set
+	OutputStream io.Writer
+
+	// Keep are '.gitignore' style patterns for paths in the OutputDir which are preserved.
+	Keep []string
+
+	// Only and Skip are '.gitignore' style patterns which select a subset of the templates to render.
+	Only []string
+	Skip []string
+
+	// AllowUnsafeOutputDir allows cleaning an OutputDir that would normally be refused (e.g. one containing '.git').
+	AllowUnsafeOutputDir bool
+
+	// StrictValues fails (instead of warning) if the values contain keys which are not in the 'default_values.yaml'
+	// of the generator source (or its 'values_schema.json').
+	StrictValues bool
+
+	// AllowInvalidYAML only warns (instead of failing) if any generated YAML files are invalid.
+	AllowInvalidYAML bool
+
+	// Validate checks the generated Kubernetes objects against their JSON schemas, which are read from the
+	// SchemaLocations, then from the 'schemas' folder of the generator source.
+	Validate        bool
+	KubeVersion     string
+	SchemaLocations []string
+
+	// ValidationOutput receives the results of Validate as JSON, instead of them being logged.
+	ValidationOutput io.Writer
+
+	// ScanSecrets scans the generated manifests for possible secret leaks.
+	ScanSecrets bool
+
+	// AllowSecrets only warns (instead of failing) if ScanSecrets finds possible secret leaks.
+	AllowSecrets bool
+
+	// Logger receives the log messages, they are discarded if it is nil.
+	Logger Logger
+}
+
+// Generator generates the manifests for a single set of Options.
+// A Generator may be used for many runs (for example, every time the values change), but not concurrently.
+type Generator struct {
+	opts Options
+	log  Logger
+}
+
+// New creates a Generator, after verifying that the Options are consistent.
+func New(opts Options) (*Generator, error) {
+	sourceCount := countTrue(opts.SourceVersion != "", opts.SourcePath != "", opts.Source != nil)
+	if sourceCount != 1 {
+		return nil, exitcode.New(exitcode.KindInvalidFlags, "exactly one of SourceVersion, SourcePath or Source must be set")
+	}
+	outputCount := countTrue(opts.OutputDir != "", opts.OutputArchive != "", opts.OutputStream != nil)
+	if outputCount != 1 {
+		return nil, exitcode.New(exitcode.KindInvalidFlags, "exactly one of OutputDir, OutputArchive or OutputStream must be set")
+	}
+	if opts.OutputArchive != "" && !generate.IsSupportedArchive(opts.OutputArchive) {
+		return nil, exitcode.New(exitcode.KindInvalidFlags, "the OutputArchive '%s' must end with '.tar.gz', '.tgz' or '.zip'", opts.OutputArchive)
+	}
+	for key := range opts.Set {
+		if key == "" {
+			return nil, exitcode.New(exitcode.KindInvalidFlags, "the Set overrides must not have an empty key")
+		}
+	}
+
+	if opts.KubeVersion == "" {
+		opts.KubeVersion = DefaultKubeVersion
+	}
+	g := &Generator{opts: opts, log: opts.Logger}
+	if g.log == nil {
+		g.log = logging.Discard()
+	}
+	return g, nil
+}
+
+// Values returns the ValuesFiles and Values of the Generator, in order of increasing precedence.
+func (g *Generator) Values() []Values {
+	return g.opts.allValues()
+}
+
+// CheckInputs verifies that the values files exist and can be read, and that the values are valid YAML mappings
+// without duplicate keys (values from URLs are only checked when they are downloaded by Generate).
+// It is called by Generate before the generator source is acquired, but may be called directly to check
+// the inputs of many Generators before any of them download a source.
+func (g *Generator) CheckInputs() error {
+	// TODO: check the YAML schema against a spec that is defined in the generator source
+	return checkValues(g.opts.allValues())
+}
+
+// Generate renders the manifests, checks them, and writes them to the output target.
+// The returned Report is never nil, and describes the run even if it failed.
+//
+// If the context is cancelled, generation stops at the next safe point, the temporary files are removed,
+// and the `--output-dir` is left unchanged (unless it was already being written).
+func (g *Generator) Generate(ctx context.Context) (*Report, error) {
+	r := &run{Generator: g, report: NewReport(version.GetVersion())}
+	err := canceledError(ctx, r.generate(ctx))
+	r.report.Finish(err)
+	return r.report, err
+}
+
+// run is the state of a single call to Generate
+type run struct {
+	*Generator
+	report *Report
+}
+
+func (r *run) generate(ctx context.Context) error {
+	r.recordInputs()
+	err := r.CheckInputs()
+	if err != nil {
+		return err
+	}
+
+	source := r.opts.Source
+	if source == nil {
+		source, err = OpenSource(ctx, SourceOptions{Version: r.opts.SourceVersion, Path: r.opts.SourcePath, Logger: r.log})
+		if err != nil {
+			return err
+		}
+		defer func() {
+			err := source.Close()
+			if err != nil {
+				r.log.Warnf("%v", err)
+			}
+		}()
+	}
+	r.report.Source = ReportSource{
+		Version: source.version,
+		Path:    source.path,
+		Hash:    source.hash,
+		Origin:  source.origin,
+	}
+	r.report.EndPhase("source")
+
+	return r.generateFromSource(ctx, source)
+}
+
+// recordInputs records the values and output target in the report
+func (r *run) recordInputs() {
+	for _, v := range r.opts.allValues() {
+		r.report.Values = append(r.report.Values, v.Name())
+	}
+	switch {
+	case r.opts.OutputArchive != "":
+		r.report.Output = ReportOutput{Type: "archive", Path: r.opts.OutputArchive}
+	case r.opts.OutputStream != nil:
+		r.report.Output = ReportOutput{Type: "stdout"}
+	default:
+		r.report.Output = ReportOutput{Type: "directory", Path: r.opts.OutputDir}
+	}
+}
+
+// generateFromSource renders the manifests from an acquired generator source, and writes them to the output target.
+// Each call uses its own temporary target directory (for the templates, runtime templates and rendered output),
+// so that many targets may be rendered concurrently from the same source.
+func (r *run) generateFromSource(ctx context.Context, source *Source) error {
+	// create a temporary directory for this target, and defer a function to clean it up after this function returns
+	tempTargetPath, err := os.MkdirTemp("", "deploykf-generator-target-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %v", err)
+	}
+	r.log.Debugf("created temporary directory: %s", tempTargetPath)
+	defer func() {
+		err := os.RemoveAll(tempTargetPath)
+		if err != nil {
+			r.log.Warnf("failed to remove temporary directory '%s': %v", tempTargetPath, err)
+		}
+	}()
+
+	// copy the templates into the target directory
+	//  - note, phase 1 writes `.gomplateignore` files into the templates folder, and `--only` and `--skip`
+	//    remove templates from it, so each target needs its own copy
+	templatesPath := filepath.Join(tempTargetPath, "templates")
+	err = generate.CopyFolder(ctx, filepath.Join(source.dir, "templates"), templatesPath)
+	if err != nil {
+		return err
+	}
+	helpersPath := filepath.Join(source.dir, "helpers")
+	defaultValuesPath := source.DefaultValuesPath()
+
+	// merge the user-provided values and set overrides in memory, so they are never written to disk
+	//  - note, if there are none, the templates only see the `default_values.yaml`
+	var userValues []byte
+	allValues := r.opts.allValues()
+	parsedValues, err := r.readAllValues(ctx, allValues)
+	if err != nil {
+		return err
+	}
+	err = r.checkUnknownKeys(parsedValues, r.opts.Set, defaultValuesPath, source.ValuesSchemaPath())
+	if err != nil {
+		return err
+	}
+	mergedValues := mergeValues(parsedValues, r.opts.Set)
+	if len(allValues) > 0 || len(r.opts.Set) > 0 {
+		userValues, err = marshalValues(mergedValues)
+		if err != nil {
+			return err
+		}
+		r.log.Debugf("merged %d values and %d set overrides in memory", len(allValues), len(r.opts.Set))
+	}
+
+	// write runtime config templates
+	//  - note, we are writing these files into the target folder, not the output folder
+	//  - note, when writing an archive, templates see the archive path (without extension) as the output dir
+	//  - note, when writing to a stream, templates see "." as the output dir
+	runtimePath := filepath.Join(tempTargetPath, "runtime")
+	runtimeOutputDir := r.opts.OutputDir
+	if r.opts.OutputArchive != "" {
+		runtimeOutputDir = generate.TrimArchiveExtension(r.opts.OutputArchive)
+	} else if r.opts.OutputStream != nil {
+		runtimeOutputDir = "."
+	}
+	r.log.Debugf("writing runtime templates to '%s' with output dir: %s", runtimePath, runtimeOutputDir)
+	err = generate.WriteRuntimeTemplates(runtimePath, templatesPath, runtimeOutputDir)
+	if err != nil {
+		return err
+	}
+
+	gomplateInputs := gomplateInputs{
+		defaultValuesPath: defaultValuesPath,
+		userValues:        userValues,
+		helpersPath:       helpersPath,
+		runtimePath:       runtimePath,
+	}
+
+	// GENERATOR PHASE 1: render `.gomplateignore_template` files
+	//  - note, we are rendering the `.gomplateignore` files into the target templates folder, not the output folder
+	phase1 := gomplatePhase{
+		name:        "phase 1",
+		inputDir:    templatesPath,
+		outputDir:   templatesPath,
+		excludeGlob: []string{"*", "!*.gomplateignore_template"},
+		outputPath: func(path string) string {
+			return strings.ReplaceAll(path, ".gomplateignore_template", ".gomplateignore")
+		},
+	}
+	err = r.renderTemplates(ctx, phase1, gomplateInputs)
+	if err != nil {
+		return exitcode.Wrap(exitcode.KindRenderFailed, generate.RewriteTemplateError(err, tempTargetPath, source.dir))
+	}
+	r.report.EndPhase("phase1")
+
+	// remove the templates which are not selected by `--only` and `--skip`
+	//  - note, this happens after phase 1, so the `.gomplateignore` files of the generator are unaffected
+	templateSelector := generate.NewTemplateSelector(r.opts.Only, r.opts.Skip)
+	if templateSelector.IsPartial() {
+		selectedCount, removedCount, err := generate.RemoveUnselectedTemplates(templatesPath, templateSelector)
+		if err != nil {
+			return err
+		}
+		r.log.Debugf("selected %d templates, and skipped %d templates", selectedCount, removedCount)
+		if selectedCount == 0 {
+			r.warn(CodeNoTemplatesSelected, "no templates were selected by the --only and --skip patterns")
+		}
+		r.report.Output.Partial = true
+	}
+
+	// GENERATOR PHASE 2: render to a staging folder
+	//  - note, we render into the temporary directory first, so that kept paths are never overwritten
+	stagingPath := filepath.Join(tempTargetPath, "output")
+	r.log.Debugf("staging path: %s", stagingPath)
+	phase2 := gomplatePhase{
+		name:      "phase 2",
+		inputDir:  templatesPath,
+		outputDir: stagingPath,
+	}
+	err = r.renderTemplates(ctx, phase2, gomplateInputs)
+	if err != nil {
+		return exitcode.Wrap(exitcode.KindRenderFailed, generate.RewriteTemplateError(err, tempTargetPath, source.dir))
+	}
+	renderedFiles, err := generate.ListFiles(stagingPath)
+	if err != nil {
+		return err
+	}
+	r.report.Files.Rendered = len(renderedFiles)
+	r.report.EndPhase("phase2")
+
+	// verify that every generated YAML file is valid
+	yamlErrors, err := generate.ValidateYAMLFiles(stagingPath, "templates")
+	if err != nil {
+		return err
+	}
+	for _, yamlErr := range yamlErrors {
+		if r.opts.AllowInvalidYAML {
+			r.warn(CodeInvalidYAML, "invalid YAML: %s", yamlErr)
+		} else {
+			r.fail(CodeInvalidYAML, "invalid YAML: %s", yamlErr)
+		}
+	}
+	if len(yamlErrors) > 0 && !r.opts.AllowInvalidYAML {
+		return exitcode.New(exitcode.KindCheckFailed, "found %d generated files with invalid YAML (use --allow-invalid-yaml to ignore)", len(yamlErrors))
+	}
+
+	// verify that every generated Kubernetes object matches its schema
+	if r.opts.Validate {
+		err = r.validateKubernetesObjects(stagingPath, filepath.Join(source.dir, "schemas"))
+		if err != nil {
+			return err
+		}
+	}
+
+	// verify that the generated manifests don't leak secrets
+	if r.opts.ScanSecrets {
+		err = r.scanForSecrets(stagingPath, source.dir, defaultValuesPath, mergedValues)
+		if err != nil {
+			return err
+		}
+	}
+	r.report.EndPhase("validate")
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	// prepare the `--output-dir`
+	//  - note, this is not needed when writing an `--output-archive` or stream
+	//  - note, this happens after rendering and checking, so an error (or cancellation) before this point
+	//    leaves the previous manifests in place, and once cleaning starts the output is always completed
+	var keepMatcher *generate.KeepMatcher
+	var runInfo *generate.RunInfo
+	if r.opts.OutputDir != "" {
+		// read the patterns for paths that should be kept in the `--output-dir`
+		//  - note, this must happen before cleaning, as the `.deploykf_keep` file is read from the `--output-dir`
+		keepMatcher, err = generate.NewKeepMatcher(r.opts.OutputDir, r.opts.Keep)
+		if err != nil {
+			return err
+		}
+
+		// clean the `--output-dir` if it's safe to do so
+		removedCount, err := generate.CleanOutputDirectory(r.opts.OutputDir, keepMatcher, r.opts.AllowUnsafeOutputDir)
+		if err != nil {
+			return err
+		}
+		r.log.Debugf("removed %d files from output directory: %s", removedCount, r.opts.OutputDir)
+		r.report.Files.Removed = removedCount
+
+		// create marker file in the `--output-dir`
+		//  - will create the directory if it doesn't already exist
+		//  - the marker will contain JSON with information like run time and source version
+		runInfo, err = generate.CreateMarkerFile(r.opts.OutputDir, source.version, source.path, source.hash, version.GetVersion())
+		if err != nil {
+			return err
+		}
+		r.markPartial(runInfo, templateSelector)
+		r.report.EndPhase("clean")
+	}
+
+	// write the rendered manifests to the output stream
+	if r.opts.OutputStream != nil {
+		skippedFiles, err := generate.WriteYAMLStream(stagingPath, r.opts.OutputStream)
+		if err != nil {
+			return err
+		}
+		for _, skippedFile := range skippedFiles {
+			r.warn(CodeNonYAMLSkipped, "skipped non-YAML file: %s", skippedFile)
+		}
+		r.report.Files.Skipped = len(skippedFiles)
+		r.report.EndPhase("output")
+		return nil
+	}
+
+	// package the rendered manifests into the `--output-archive`
+	if r.opts.OutputArchive != "" {
+		// write the marker into the staging folder, so it is included in the archive
+		//  - note, we don't set `generated_at`, so that identical inputs produce identical archives
+		err = os.MkdirAll(stagingPath, 0755)
+		if err != nil {
+			return err
+		}
+		archiveRunInfo := &generate.RunInfo{
+			SourceVersion: source.version,
+			SourcePath:    source.path,
+			SourceHash:    source.hash,
+			CLIVersion:    version.GetVersion(),
+			OutputFiles:   renderedFiles,
+		}
+		r.markPartial(archiveRunInfo, templateSelector)
+		err = generate.WriteMarkerFile(stagingPath, archiveRunInfo)
+		if err != nil {
+			return err
+		}
+
+		err = generate.WriteArchive(stagingPath, r.opts.OutputArchive)
+		if err != nil {
+			return err
+		}
+		r.report.EndPhase("output")
+
+		// log the output archive
+		r.log.Infof("Generated manifests archive at: %s", r.opts.OutputArchive)
+
+		return nil
+	}
+
+	// copy the rendered manifests from the staging folder into the `--output-dir`
+	outputFiles, skippedFiles, err := generate.PublishOutput(stagingPath, r.opts.OutputDir, keepMatcher)
+	if err != nil {
+		return err
+	}
+	for _, skippedFile := range skippedFiles {
+		r.warn(CodeKeptPath, "not overwriting kept path: %s", skippedFile)
+	}
+	r.report.Files.Skipped = len(skippedFiles)
+
+	// record the generated files in the marker, so the next run only removes these files
+	runInfo.OutputFiles = outputFiles
+	err = generate.WriteMarkerFile(r.opts.OutputDir, runInfo)
+	if err != nil {
+		return err
+	}
+	r.report.EndPhase("output")
+
+	// log the output directory
+	r.log.Infof("Generated manifests at: %s", r.opts.OutputDir)
+
+	return nil
+}
+
+// markPartial records the `--only` and `--skip` patterns in the marker, if only some templates are rendered
+func (r *run) markPartial(runInfo *generate.RunInfo, selector *generate.TemplateSelector) {
+	if !selector.IsPartial() {
+		return
+	}
+	runInfo.Partial = true
+	runInfo.Only = r.opts.Only
+	runInfo.Skip = r.opts.Skip
+}
+
+// warn logs a warning, and records it in the report
+func (r *run) warn(code string, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	r.log.Warnf("%s", message)
+	r.report.AddWarning(code, message)
+}
+
+// fail logs an error (which doesn't immediately stop the run), and records it in the report
+func (r *run) fail(code string, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	r.log.Errorf("%s", message)
+	r.report.AddError(code, message)
+}
+
+// canceledError gives an error the Kind `exitcode.KindCanceled`, if the context was cancelled (or timed out)
+func canceledError(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() == nil || exitcode.KindOf(err) == exitcode.KindCanceled {
+		return err
+	}
+	message := "generation was canceled"
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		message = "generation timed out"
+	}
+	return &exitcode.Error{Kind: exitcode.KindCanceled, Err: fmt.Errorf("%s: %w", message, err)}
+}
+
+// countTrue returns the number of true values
+func countTrue(values ...bool) int {
+	count := 0
+	for _, value := range values {
+		if value {
+			count++
+		}
+	}
+	return count
+}
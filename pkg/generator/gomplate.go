@@ -0,0 +1,249 @@
+package generator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hairyhenderson/go-fsimpl/filefs"
+	"github.com/hairyhenderson/gomplate/v3"
+	"github.com/hairyhenderson/gomplate/v3/data"
+	"github.com/spf13/afero"
+	"github.com/zealic/xignore"
+)
+
+// gomplateLock serializes gomplate runs, as gomplate keeps its metrics in global state which is not safe
+// for concurrent use (everything else about a target may be generated concurrently)
+var gomplateLock = make(chan struct{}, 1)
+
+// the name of the files which exclude templates from rendering (like a `.gitignore` file)
+const gomplateIgnoreFile = ".gomplateignore"
+
+// the alias of the datasource which contains the merged user-provided values
+const userValuesAlias = "Values_user"
+
+// the alias of the datasource which contains the `default_values.yaml` of the generator source
+const defaultValuesAlias = "Values_default"
+
+// gomplatePhase describes a rendering of the templates in a folder
+type gomplatePhase struct {
+	name        string              // the name of the phase, used in logs
+	inputDir    string              // the folder containing the templates
+	outputDir   string              // the folder into which the templates are rendered
+	excludeGlob []string            // patterns for templates which are not rendered (after the `.gomplateignore` files)
+	outputPath  func(string) string // maps a template path (relative to inputDir) to an output path (if not nil)
+}
+
+// gomplateInputs are the values and nested templates which are available to the templates of every phase
+type gomplateInputs struct {
+	defaultValuesPath string // the `default_values.yaml` of the generator source
+	userValues        []byte // the merged user-provided values as YAML, or nil if there are none
+	helpersPath       string // the folder of `helpers` templates
+	runtimePath       string // the folder of `runtime` templates
+}
+
+// renderTemplates renders the templates of a phase with gomplate, waiting for any concurrent runs to finish.
+// Like `gomplate --input-dir`, the templates are selected with `.gomplateignore` files, each template keeps
+// its file mode, and templates which render to only whitespace are not written.
+// NOTE: `gomplate.RunTemplates` always reads the `stdin:` datasource from `os.Stdin`, so it can't render
+// in-memory values (`TestRenderTemplatesMatchesRunTemplates` checks that both render identical output)
+// NOTE: a gomplate render can't be interrupted, so the context is only checked before and after the render
+func (r *run) renderTemplates(ctx context.Context, phase gomplatePhase, inputs gomplateInputs) error {
+	r.logGomplatePhase(phase, inputs)
+
+	select {
+	case gomplateLock <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-gomplateLock }()
+
+	templates, err := gatherTemplates(phase)
+	if err != nil {
+		return err
+	}
+	r.log.Debugf("%s: gathered %d templates", phase.name, len(templates))
+
+	renderer := gomplate.NewRenderer(gomplate.Options{
+		Datasources: gomplateDataSources(inputs),
+		Context:     gomplateContexts(inputs),
+		Templates:   gomplateTemplates(inputs),
+		LDelim:      "{{<",
+		RDelim:      ">}}",
+	})
+
+	// the user-provided values are read from memory, through gomplate's `stdin:` datasource
+	ctx = gomplate.ContextWithFSProvider(ctx, filefs.FS)
+	ctx = data.ContextWithStdin(ctx, bytes.NewReader(inputs.userValues))
+
+	err = renderer.RenderTemplates(ctx, templates)
+	if err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// gatherTemplates reads the templates of a phase, and prepares a lazy writer for each of their output files
+func gatherTemplates(phase gomplatePhase) ([]gomplate.Template, error) {
+	inputDir := filepath.Clean(phase.inputDir)
+	inputDirInfo, err := os.Stat(inputDir)
+	if err != nil {
+		return nil, fmt.Errorf("couldn't stat %s: %w", inputDir, err)
+	}
+
+	matcher := xignore.NewMatcher(afero.NewOsFs())
+	matches, err := matcher.Matches(inputDir, &xignore.MatchesOptions{
+		Ignorefile:    gomplateIgnoreFile,
+		Nested:        true,
+		AfterPatterns: phase.excludeGlob,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ignore matching failed for %s: %w", inputDir, err)
+	}
+
+	templates := make([]gomplate.Template, 0, len(matches.UnmatchedFiles))
+	for _, file := range matches.UnmatchedFiles {
+		inFile := filepath.Join(inputDir, file)
+		outFile := file
+		if phase.outputPath != nil {
+			outFile = phase.outputPath(file)
+		}
+		outFile = filepath.Join(phase.outputDir, outFile)
+
+		inFileInfo, err := os.Stat(inFile)
+		if err != nil {
+			return nil, err
+		}
+		text, err := os.ReadFile(inFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", inFile, err)
+		}
+
+		// the parent folders are created for every template (even if it renders to only whitespace)
+		err = os.MkdirAll(filepath.Dir(outFile), inputDirInfo.Mode())
+		if err != nil {
+			return nil, err
+		}
+
+		templates = append(templates, gomplate.Template{
+			Name:   inFile,
+			Text:   string(text),
+			Writer: &lazyFileWriter{path: outFile, mode: inFileInfo.Mode()},
+		})
+	}
+	return templates, nil
+}
+
+// lazyFileWriter only creates its file when the first non-whitespace byte is written,
+// so that templates which render to only whitespace don't produce a file
+type lazyFileWriter struct {
+	path       string
+	mode       os.FileMode
+	file       *os.File
+	whitespace bytes.Buffer
+}
+
+func (w *lazyFileWriter) Write(p []byte) (int, error) {
+	if w.file == nil {
+		if len(bytes.TrimLeft(p, " \t\n\r\v")) == 0 {
+			return w.whitespace.Write(p)
+		}
+		file, err := os.OpenFile(w.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, w.mode)
+		if err != nil {
+			return 0, err
+		}
+		w.file = file
+		_, err = w.whitespace.WriteTo(w.file)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return w.file.Write(p)
+}
+
+func (w *lazyFileWriter) Close() error {
+	if w.file == nil {
+		return nil
+	}
+	return w.file.Close()
+}
+
+// logGomplatePhase logs the paths, datasources and templates of a phase at the debug level
+func (r *run) logGomplatePhase(phase gomplatePhase, inputs gomplateInputs) {
+	r.log.Debugf("%s: gomplate input dir: %s", phase.name, phase.inputDir)
+	r.log.Debugf("%s: gomplate output dir: %s", phase.name, phase.outputDir)
+	if len(phase.excludeGlob) > 0 {
+		r.log.Debugf("%s: gomplate exclude globs: %s", phase.name, strings.Join(phase.excludeGlob, ", "))
+	}
+	dataSources := gomplateDataSources(inputs)
+	for _, alias := range sortedAliases(dataSources) {
+		r.log.Debugf("%s: gomplate datasource: %s=%s", phase.name, alias, dataSources[alias].URL)
+	}
+	contexts := gomplateContexts(inputs)
+	for _, alias := range sortedAliases(contexts) {
+		r.log.Debugf("%s: gomplate context: %s=%s", phase.name, alias, contexts[alias].URL)
+	}
+	templates := gomplateTemplates(inputs)
+	for _, alias := range sortedAliases(templates) {
+		r.log.Debugf("%s: gomplate template: %s=%s", phase.name, alias, templates[alias].URL)
+	}
+	if inputs.userValues != nil {
+		r.log.Debugf("%s: gomplate in-memory values: %d bytes", phase.name, len(inputs.userValues))
+	}
+}
+
+// build the datasources for our gomplate renderer
+func gomplateDataSources(inputs gomplateInputs) map[string]gomplate.Datasource {
+	dataSources := map[string]gomplate.Datasource{
+		defaultValuesAlias: {URL: fileURL(inputs.defaultValuesPath)},
+	}
+
+	// the user-provided values are merged in memory (see `mergeValues`), so they are a single datasource
+	if inputs.userValues != nil {
+		dataSources[userValuesAlias] = gomplate.Datasource{URL: &url.URL{Scheme: "stdin", Path: "/values.yaml"}}
+	}
+
+	return dataSources
+}
+
+// build the context for our gomplate renderer
+func gomplateContexts(inputs gomplateInputs) map[string]gomplate.Datasource {
+	// we only use `merge` if there are user-provided values
+	// NOTE: merges happen from right to left, so the user-provided values take precedence over the defaults
+	if inputs.userValues == nil {
+		return map[string]gomplate.Datasource{
+			"Values": {URL: fileURL(inputs.defaultValuesPath)},
+		}
+	}
+	return map[string]gomplate.Datasource{
+		"Values": {URL: &url.URL{Scheme: "merge", Opaque: userValuesAlias + "|" + defaultValuesAlias}},
+	}
+}
+
+// build the nested templates for our gomplate renderer
+func gomplateTemplates(inputs gomplateInputs) map[string]gomplate.Datasource {
+	return map[string]gomplate.Datasource{
+		"helpers": {URL: fileURL(inputs.helpersPath)},
+		"runtime": {URL: fileURL(inputs.runtimePath)},
+	}
+}
+
+// fileURL returns the `file:` URL of an absolute path, like gomplate would parse it
+func fileURL(path string) *url.URL {
+	return &url.URL{Scheme: "file", Path: filepath.ToSlash(path)}
+}
+
+// sortedAliases returns the aliases of some gomplate datasources, in order
+func sortedAliases(dataSources map[string]gomplate.Datasource) []string {
+	aliases := make([]string, 0, len(dataSources))
+	for alias := range dataSources {
+		aliases = append(aliases, alias)
+	}
+	sort.Strings(aliases)
+	return aliases
+}
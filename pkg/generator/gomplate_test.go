@@ -0,0 +1,209 @@
+package generator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hairyhenderson/gomplate/v3"
+
+	"github.com/deployKF/cli/internal/generate"
+	"github.com/deployKF/cli/internal/logging"
+)
+
+// gomplateTestSource are the files of the generator source which is rendered by the tests
+var gomplateTestSource = map[string]string{
+	"default_values.yaml":                  "app:\n  name: default\n  replicas: 1\n  extra: false\n",
+	"helpers/greet.tpl":                    "hello {{< .Values.app.name >}}",
+	"runtime/output.tpl":                   "./output",
+	"templates/.gomplateignore_template":   "{{<- if not .Values.app.extra >}}\nextra/\n{{<- end >}}\n",
+	"templates/app/deploy.yaml":            "name: {{< .Values.app.name >}}\nreplicas: {{< .Values.app.replicas >}}\ngreeting: {{< template \"helpers/greet.tpl\" . >}}\n",
+	"templates/app/empty.yaml":             "{{<- if .Values.app.extra >}}\nkind: Empty\n{{<- end >}}\n\n",
+	"templates/app/leading-space.yaml":     "\n\n  {{<- \"\" >}}\nkind: Spaced\n",
+	"templates/extra/skipped.yaml":         "kind: Extra\n",
+	"templates/docs/.gomplateignore":       "*.md\n",
+	"templates/docs/README.md":             "ignored {{< .Values.app.name >}}\n",
+	"templates/docs/notes/nested.yaml":     "output: {{< template \"runtime/output.tpl\" >}}\n",
+	"templates/docs/.gomplateignore_extra": "# not an ignore file\n",
+}
+
+// writeGomplateTestSource writes the generator source, and returns its directory
+func writeGomplateTestSource(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	for relPath, content := range gomplateTestSource {
+		path := filepath.Join(dir, filepath.FromSlash(relPath))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a template with a different file mode
+	script := filepath.Join(dir, "templates", "run.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho {{< .Values.app.name >}}\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(script, 0755); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+// readTestTree returns the contents and file modes of the files under a directory, by their path
+func readTestTree(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	files, err := generate.ListFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := map[string]string{}
+	for _, relPath := range files {
+		path := filepath.Join(dir, filepath.FromSlash(relPath))
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tree[relPath] = info.Mode().String() + "\n" + string(data)
+	}
+	return tree
+}
+
+// renderWithRunTemplates renders both phases like the generator did before `renderTemplates`,
+// with the fork's `gomplate.RunTemplates` and the user-provided values in a file
+func renderWithRunTemplates(t *testing.T, sourceDir string, templatesPath string, outputPath string, userValues []byte) {
+	t.Helper()
+	userValuesPath := filepath.Join(t.TempDir(), "values.yaml")
+	if err := os.WriteFile(userValuesPath, userValues, 0600); err != nil {
+		t.Fatal(err)
+	}
+	defaultValuesPath := filepath.Join(sourceDir, "default_values.yaml")
+	dataSources := []string{
+		userValuesAlias + "=" + userValuesPath,
+		defaultValuesAlias + "=" + defaultValuesPath,
+	}
+	contexts := []string{"Values=merge:" + userValuesAlias + "|" + defaultValuesAlias}
+	templates := []string{
+		"helpers=" + filepath.Join(sourceDir, "helpers"),
+		"runtime=" + filepath.Join(sourceDir, "runtime"),
+	}
+
+	err := gomplate.RunTemplates(&gomplate.Config{ //nolint:staticcheck
+		InputDir:      templatesPath,
+		OutputMap:     templatesPath + `/{{< .in | strings.ReplaceAll ".gomplateignore_template" ".gomplateignore" >}}`,
+		ExcludeGlob:   []string{"*", "!*.gomplateignore_template"},
+		LDelim:        "{{<",
+		RDelim:        ">}}",
+		DataSources:   dataSources,
+		Contexts:      contexts,
+		Templates:     templates,
+		SuppressEmpty: true,
+	})
+	if err != nil {
+		t.Fatalf("phase 1 with RunTemplates: %v", err)
+	}
+	err = gomplate.RunTemplates(&gomplate.Config{ //nolint:staticcheck
+		InputDir:      templatesPath,
+		OutputDir:     outputPath,
+		LDelim:        "{{<",
+		RDelim:        ">}}",
+		DataSources:   dataSources,
+		Contexts:      contexts,
+		Templates:     templates,
+		SuppressEmpty: true,
+	})
+	if err != nil {
+		t.Fatalf("phase 2 with RunTemplates: %v", err)
+	}
+}
+
+// renderWithRenderTemplates renders both phases like the generator does, with the user-provided values in memory
+func renderWithRenderTemplates(t *testing.T, sourceDir string, templatesPath string, outputPath string, userValues []byte) {
+	t.Helper()
+	r := &run{Generator: &Generator{log: logging.Discard()}}
+	inputs := gomplateInputs{
+		defaultValuesPath: filepath.Join(sourceDir, "default_values.yaml"),
+		userValues:        userValues,
+		helpersPath:       filepath.Join(sourceDir, "helpers"),
+		runtimePath:       filepath.Join(sourceDir, "runtime"),
+	}
+
+	err := r.renderTemplates(context.Background(), gomplatePhase{
+		name:        "phase 1",
+		inputDir:    templatesPath,
+		outputDir:   templatesPath,
+		excludeGlob: []string{"*", "!*.gomplateignore_template"},
+		outputPath: func(path string) string {
+			return strings.ReplaceAll(path, ".gomplateignore_template", ".gomplateignore")
+		},
+	}, inputs)
+	if err != nil {
+		t.Fatalf("phase 1 with renderTemplates: %v", err)
+	}
+	err = r.renderTemplates(context.Background(), gomplatePhase{
+		name:      "phase 2",
+		inputDir:  templatesPath,
+		outputDir: outputPath,
+	}, inputs)
+	if err != nil {
+		t.Fatalf("phase 2 with renderTemplates: %v", err)
+	}
+}
+
+func TestRenderTemplatesMatchesRunTemplates(t *testing.T) {
+	sourceDir := writeGomplateTestSource(t)
+	userValues := []byte("app:\n  name: user\n  list: [1, 2]\n")
+
+	// each path renders its own copy of the templates, as phase 1 writes into the templates folder
+	render := func(renderFunc func(*testing.T, string, string, string, []byte)) (map[string]string, map[string]string) {
+		dir := t.TempDir()
+		templatesPath := filepath.Join(dir, "templates")
+		outputPath := filepath.Join(dir, "output")
+		err := generate.CopyFolder(context.Background(), filepath.Join(sourceDir, "templates"), templatesPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		renderFunc(t, sourceDir, templatesPath, outputPath, userValues)
+		return readTestTree(t, templatesPath), readTestTree(t, outputPath)
+	}
+	wantTemplates, wantOutput := render(renderWithRunTemplates)
+	gotTemplates, gotOutput := render(renderWithRenderTemplates)
+
+	// the rendered output is not trivial (so the comparison is meaningful)
+	if !strings.Contains(wantOutput["app/deploy.yaml"], "name: user\nreplicas: 1\ngreeting: hello user\n") {
+		t.Fatalf("unexpected output of RunTemplates: %q", wantOutput["app/deploy.yaml"])
+	}
+	if _, ok := wantOutput["extra/skipped.yaml"]; ok {
+		t.Fatalf("expected RunTemplates to skip the templates excluded by a rendered .gomplateignore")
+	}
+
+	compareTrees(t, "templates folder", gotTemplates, wantTemplates)
+	compareTrees(t, "output folder", gotOutput, wantOutput)
+}
+
+// compareTrees reports every file which differs between two trees from `readTestTree`
+func compareTrees(t *testing.T, name string, got map[string]string, want map[string]string) {
+	t.Helper()
+	for relPath, wantContent := range want {
+		gotContent, ok := got[relPath]
+		if !ok {
+			t.Errorf("%s: missing '%s'", name, relPath)
+			continue
+		}
+		if gotContent != wantContent {
+			t.Errorf("%s: '%s' = %q, want %q", name, relPath, gotContent, wantContent)
+		}
+	}
+	for relPath := range got {
+		if _, ok := want[relPath]; !ok {
+			t.Errorf("%s: unexpected '%s'", name, relPath)
+		}
+	}
+}
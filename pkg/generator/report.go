@@ -0,0 +1,141 @@
+package generator
+
+import (
+	"encoding/json"
+	"os"
+	"time"
+
+	"github.com/deployKF/cli/internal/exitcode"
+	"github.com/deployKF/cli/internal/generate"
+)
+
+// Codes for the warnings and errors in a Report.
+// NOTE: the final error of a run uses the name of its `exitcode.Kind` as its code
+const (
+	CodeKeptPath            = "kept_path"
+	CodeInvalidYAML         = "invalid_yaml"
+	CodeMissingSchema       = "missing_schema"
+	CodeSchemaViolation     = "schema_violation"
+	CodeSecretLeak          = "secret_leak"
+	CodeNonYAMLSkipped      = "non_yaml_skipped"
+	CodeNoTemplatesSelected = "no_templates_selected"
+	CodeUnknownValuesKey    = "unknown_values_key"
+)
+
+// Results of the checks on the generated manifests, which are included in a Report.
+type (
+	KubeValidationResult = generate.KubeValidationResult
+	KubeObjectError      = generate.KubeObjectError
+	KubeObjectRef        = generate.KubeObjectRef
+	SecretFinding        = generate.SecretFinding
+)
+
+// Report is a machine-readable summary of a run of a Generator (or the `deploykf generate` command).
+type Report struct {
+	Success    bool   `json:"success"`
+	ExitCode   int    `json:"exit_code"`
+	CLIVersion string `json:"cli_version"`
+	StartedAt  string `json:"started_at"`
+
+	Source ReportSource `json:"source"`
+	Values []string     `json:"values"`
+	Output ReportOutput `json:"output"`
+	Files  ReportFiles  `json:"files"`
+
+	Timings  []ReportTiming  `json:"timings"`
+	Warnings []ReportMessage `json:"warnings"`
+	Errors   []ReportMessage `json:"errors"`
+
+	Validation     *KubeValidationResult `json:"validation,omitempty"`
+	SecretFindings []SecretFinding       `json:"secret_findings,omitempty"`
+
+	phaseStart time.Time
+}
+
+// ReportSource describes the generator source that was used.
+type ReportSource struct {
+	Version string `json:"version,omitempty"`
+	Path    string `json:"path,omitempty"`
+	Hash    string `json:"hash,omitempty"`
+	Origin  string `json:"origin,omitempty"`
+}
+
+// ReportOutput describes where the generated manifests were written.
+type ReportOutput struct {
+	Type    string `json:"type"` // one of: "directory", "archive", "stdout"
+	Path    string `json:"path,omitempty"`
+	Partial bool   `json:"partial,omitempty"` // true if only some templates were rendered
+}
+
+// ReportFiles counts the files affected by the run.
+type ReportFiles struct {
+	Rendered int `json:"rendered"`
+	Skipped  int `json:"skipped"`
+	Removed  int `json:"removed"`
+}
+
+// ReportTiming is the duration of a single phase of the run.
+type ReportTiming struct {
+	Phase      string `json:"phase"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// ReportMessage is a warning or error with a machine-readable code.
+type ReportMessage struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// NewReport creates an empty Report for a run which starts now.
+func NewReport(cliVersion string) *Report {
+	now := time.Now()
+	return &Report{
+		CLIVersion: cliVersion,
+		StartedAt:  now.UTC().Format(time.RFC3339),
+		Values:     []string{},
+		Timings:    []ReportTiming{},
+		Warnings:   []ReportMessage{},
+		Errors:     []ReportMessage{},
+		phaseStart: now,
+	}
+}
+
+// EndPhase records the time since the previous phase ended (or the run started) as the duration of the named phase.
+func (r *Report) EndPhase(phase string) {
+	now := time.Now()
+	r.Timings = append(r.Timings, ReportTiming{Phase: phase, DurationMs: now.Sub(r.phaseStart).Milliseconds()})
+	r.phaseStart = now
+}
+
+// AddWarning records a warning with the provided code.
+func (r *Report) AddWarning(code string, message string) {
+	r.Warnings = append(r.Warnings, ReportMessage{Code: code, Message: message})
+}
+
+// AddError records an error with the provided code.
+func (r *Report) AddError(code string, message string) {
+	r.Errors = append(r.Errors, ReportMessage{Code: code, Message: message})
+}
+
+// Finish marks the run as complete, recording the final error (if any) and its exit code.
+func (r *Report) Finish(err error) {
+	if err != nil {
+		r.AddError(exitcode.KindOf(err).String(), err.Error())
+	}
+	r.ExitCode = exitcode.Code(err)
+	r.Success = err == nil && len(r.Errors) == 0
+}
+
+// JSON returns the indented JSON representation of the Report.
+func (r *Report) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// WriteFile writes the Report as JSON to the provided path.
+func (r *Report) WriteFile(path string) error {
+	data, err := r.JSON()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,205 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/deployKF/cli/internal/exitcode"
+	"github.com/deployKF/cli/internal/generate"
+	"github.com/deployKF/cli/internal/logging"
+	"github.com/deployKF/cli/internal/values"
+)
+
+// Origins of a generator Source.
+const (
+	SourceOriginGithubRelease  = "github_release"
+	SourceOriginLocalZip       = "local_zip"
+	SourceOriginLocalDirectory = "local_directory"
+)
+
+// SourceOptions select the generator source to open, exactly one of Version or Path must be set.
+type SourceOptions struct {
+	// Version is a version tag from the 'deployKF/deployKF' GitHub repository, which is downloaded (and cached).
+	Version string
+
+	// Path is a local directory or '.zip' file containing a generator source.
+	Path string
+
+	// Logger receives the log messages, they are discarded if it is nil.
+	Logger Logger
+}
+
+// Source is a generator source which has been unpacked (or copied) into a temporary directory, and verified.
+// A Source is never modified after it is opened, so it may be shared by many Generators, even concurrently.
+type Source struct {
+	dir     string // the temporary directory containing the source
+	path    string // the resolved path of the source artifact
+	hash    string // the hash of the source artifact
+	origin  string // one of the `SourceOrigin*` constants
+	version string // the source version (if any)
+}
+
+// Version returns the version tag of the Source, or "" if it was opened from a local path.
+func (s *Source) Version() string {
+	return s.version
+}
+
+// Path returns the resolved path of the source artifact (a '.zip' file or a directory).
+func (s *Source) Path() string {
+	return s.path
+}
+
+// Hash returns the SHA256 hash of the source artifact.
+func (s *Source) Hash() string {
+	return s.hash
+}
+
+// Origin returns where the Source came from, one of the `SourceOrigin*` constants.
+func (s *Source) Origin() string {
+	return s.origin
+}
+
+// DefaultValuesPath returns the path of the `default_values.yaml` file in the Source.
+func (s *Source) DefaultValuesPath() string {
+	return filepath.Join(s.dir, "default_values.yaml")
+}
+
+// ValuesSchemaPath returns the path of the optional `values_schema.json` file in the Source (which may not exist).
+func (s *Source) ValuesSchemaPath() string {
+	return filepath.Join(s.dir, generate.ValuesSchemaFile)
+}
+
+// ValuesMigrationsPath returns the path of the optional `values_migrations.yaml` file in the Source (which may not exist).
+func (s *Source) ValuesMigrationsPath() string {
+	return filepath.Join(s.dir, values.MigrationsFile)
+}
+
+// Close removes the temporary directory of the Source, it must not be used afterwards.
+func (s *Source) Close() error {
+	err := os.RemoveAll(s.dir)
+	if err != nil {
+		return fmt.Errorf("failed to remove temporary directory '%s': %v", s.dir, err)
+	}
+	return nil
+}
+
+// OpenSource populates a temporary directory with a generator source, and verifies that it is supported by this
+// version of the CLI. If there is no error, the Source must be closed to remove the temporary directory.
+// If the context is cancelled, the download (or copy) is stopped, and the temporary directory is removed.
+func OpenSource(ctx context.Context, opts SourceOptions) (*Source, error) {
+	log := opts.Logger
+	if log == nil {
+		log = logging.Discard()
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, canceledError(ctx, err)
+	}
+
+	// initialise the source helper
+	// TODO: let users provide their own repo/owner for the source
+	sourceHelper := generate.NewSourceHelper()
+
+	// create a temporary directory to store our generator source
+	tempSourcePath, err := os.MkdirTemp("", "deploykf-generator-source-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %v", err)
+	}
+	log.Debugf("created temporary directory: %s", tempSourcePath)
+
+	source := &Source{
+		dir:     tempSourcePath,
+		version: opts.Version,
+	}
+	err = canceledError(ctx, source.populate(ctx, sourceHelper, opts, log))
+	if err != nil {
+		closeErr := source.Close()
+		if closeErr != nil {
+			log.Warnf("%v", closeErr)
+		}
+		return nil, err
+	}
+	return source, nil
+}
+
+// populate populates the temporary directory with the generator source, and verifies it
+func (s *Source) populate(ctx context.Context, sourceHelper *generate.SourceHelper, opts SourceOptions, log Logger) error {
+	// populate the temporary directory with the generator source
+	//  - CASE 1: if `--source-version` is provided, download that version's `.zip` file and unzip it into the temp folder
+	//  - CASE 2: if `--source-path` points to a `.zip` file, unzip it into the temp folder
+	//  - CASE 3: if `--source-path` points to a folder, copy the contents of that folder into the temp folder
+	var err error
+	if opts.Version != "" {
+		// CASE 1: download the source from GitHub
+		s.path, err = sourceHelper.DownloadAndUnpackSource(ctx, opts.Version, s.dir, log)
+		if err != nil {
+			return err
+		}
+		s.origin = SourceOriginGithubRelease
+	} else if opts.Path != "" {
+		s.path, err = filepath.EvalSymlinks(opts.Path)
+		if err != nil {
+			if os.IsNotExist(err) {
+				return exitcode.New(exitcode.KindSourceNotFound, "the provided --source-path '%s' does not exist", opts.Path)
+			}
+			return err
+		}
+		sourceIsDir, sourceIsFile, err := generate.PathExists(s.path)
+		if err != nil {
+			return err
+		}
+		if sourceIsFile && strings.HasSuffix(s.path, ".zip") {
+			// CASE 2: source is a .zip file
+			log.Infof("Using custom source file: %s", opts.Path)
+			err := generate.UnzipFile(ctx, s.path, s.dir, "generator")
+			if err != nil {
+				return exitcode.Wrap(exitcode.KindUnsupportedSource, err)
+			}
+			s.origin = SourceOriginLocalZip
+		} else if sourceIsDir {
+			// CASE 3: source is a folder
+			log.Infof("Using custom source folder: %s", opts.Path)
+			err := generate.CopyFolder(ctx, s.path, s.dir)
+			if err != nil {
+				return err
+			}
+			s.origin = SourceOriginLocalDirectory
+		} else {
+			return exitcode.New(exitcode.KindUnsupportedSource, "the provided --source-path '%s' must be a folder or a .zip file", opts.Path)
+		}
+	} else {
+		return exitcode.New(exitcode.KindInvalidFlags, "at least one of `--source-version` or `--source-path` must be provided")
+	}
+	log.Debugf("resolved generator source: %s", s.path)
+
+	// important paths from the generator source
+	templatesPath := filepath.Join(s.dir, "templates")
+	helpersPath := filepath.Join(s.dir, "helpers")
+	defaultValuesPath := filepath.Join(s.dir, "default_values.yaml")
+	markerPath := filepath.Join(s.dir, ".deploykf_generator")
+	log.Debugf("generator templates path: %s", templatesPath)
+	log.Debugf("generator helpers path: %s", helpersPath)
+	log.Debugf("generator default values path: %s", defaultValuesPath)
+	log.Debugf("generator marker path: %s", markerPath)
+
+	// verify the generator source is valid, and is supported by this version of the CLI
+	err = generate.VerifyGeneratorSource(templatesPath, helpersPath, defaultValuesPath, markerPath)
+	if err != nil {
+		return err
+	}
+
+	// calculate the hash of the generator source
+	//  - if the source was a `.zip` file, we'll use the hash of the file
+	//  - if the source was a folder, we'll use the hash of the folder
+	//    note, we'll ignore the `.gomplateignore` files when calculating the hash
+	//    see `generate.HashPath` for more details
+	s.hash, err = generate.HashPath(ctx, s.path, []string{".gomplateignore"})
+	if err != nil {
+		return err
+	}
+	log.Debugf("generator source hash: %s", s.hash)
+
+	return nil
+}
@@ -0,0 +1,351 @@
+package generator
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/deployKF/cli/internal/exitcode"
+	"github.com/deployKF/cli/internal/generate"
+	"github.com/deployKF/cli/internal/schema"
+	"github.com/deployKF/cli/internal/sops"
+	"github.com/deployKF/cli/internal/values"
+)
+
+// the URL schemes which are allowed for values (see `ValuesFromRef`)
+var valuesURLSchemes = []string{"https", "file"}
+
+// Values are configuration values for a Generator, from a file, a URL or from memory.
+// They are created with ValuesFromFile, ValuesFromRef, ValuesFromYAML or ValuesFromMap.
+type Values struct {
+	name string                 // describes the values in logs, errors and the report (like a file path)
+	path string                 // the path of a values file (if any)
+	url  string                 // the 'https://' URL of the values (if any)
+	yaml []byte                 // raw YAML values (if any)
+	data map[string]interface{} // in-memory values (if any)
+}
+
+// ValuesFromFile returns the Values of a YAML file, which is read when the manifests are generated.
+func ValuesFromFile(path string) Values {
+	return Values{name: path, path: path}
+}
+
+// ValuesFromYAML returns Values from a YAML document, which must contain a mapping at its root.
+// The name describes the values in logs, errors and the report (like "stdin").
+func ValuesFromYAML(name string, data []byte) Values {
+	return Values{name: name, yaml: data}
+}
+
+// ValuesFromMap returns Values from an in-memory mapping, which is never modified.
+// The name describes the values in logs, errors and the report.
+func ValuesFromMap(name string, data map[string]interface{}) Values {
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+	return Values{name: name, data: data}
+}
+
+// ValuesFromRef returns the Values for a reference like the `--values` flag accepts, which is one of:
+//   - "-" to read YAML from stdin (which is read immediately)
+//   - "env:NAME" to read YAML from an environment variable (which is read immediately)
+//   - an "https://" URL, which is downloaded when the manifests are generated
+//   - a "file://" URL, or a local file path
+func ValuesFromRef(ref string, stdin io.Reader) (Values, error) {
+	switch {
+	case ref == "":
+		return Values{}, exitcode.New(exitcode.KindInvalidFlags, "the provided --values must not be empty")
+
+	case ref == "-":
+		if stdin == nil {
+			return Values{}, exitcode.New(exitcode.KindInvalidFlags, "the provided --values '-' can't be used, stdin is not available")
+		}
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return Values{}, exitcode.New(exitcode.KindInvalidValues, "failed to read values from stdin: %v", err)
+		}
+		return ValuesFromYAML("stdin", data), nil
+
+	case strings.HasPrefix(ref, "env:"):
+		envName := strings.TrimPrefix(ref, "env:")
+		if envName == "" {
+			return Values{}, exitcode.New(exitcode.KindInvalidFlags, "the provided --values '%s' must name an environment variable, like 'env:DEPLOYKF_VALUES'", ref)
+		}
+		data, ok := os.LookupEnv(envName)
+		if !ok {
+			return Values{}, exitcode.New(exitcode.KindInvalidValues, "the environment variable '%s' of --values '%s' is not set", envName, ref)
+		}
+		return ValuesFromYAML(ref, []byte(data)), nil
+
+	case strings.Contains(ref, "://"):
+		return valuesFromURL(ref)
+	}
+	return ValuesFromFile(ref), nil
+}
+
+// valuesFromURL returns the Values for a URL, if its scheme is allowed
+func valuesFromURL(ref string) (Values, error) {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return Values{}, exitcode.New(exitcode.KindInvalidFlags, "the provided --values '%s' is not a valid URL: %v", ref, err)
+	}
+	switch u.Scheme {
+	case "https":
+		if u.Host == "" {
+			return Values{}, exitcode.New(exitcode.KindInvalidFlags, "the provided --values '%s' must have a host", ref)
+		}
+		return Values{name: u.Redacted(), url: ref}, nil
+	case "file":
+		if u.Host != "" && u.Host != "localhost" {
+			return Values{}, exitcode.New(exitcode.KindInvalidFlags, "the provided --values '%s' must be a local file, like 'file:///path/to/values.yaml'", ref)
+		}
+		return ValuesFromFile(u.Path), nil
+	}
+	return Values{}, exitcode.New(exitcode.KindInvalidFlags, "the provided --values '%s' has an unsupported URL scheme '%s', must be one of: %s", ref, u.Scheme, strings.Join(valuesURLSchemes, ", "))
+}
+
+// Name returns the description of the Values (for a file, this is its path).
+func (v Values) Name() string {
+	return v.name
+}
+
+// Path returns the path of the values file, or "" if the Values are not read from a local file.
+func (v Values) Path() string {
+	return v.path
+}
+
+// read returns the Values as a mapping, downloading them if they are a URL, and decrypting them in memory
+// if they are encrypted with SOPS (in which case, decrypted is true)
+func (v Values) read(ctx context.Context) (data map[string]interface{}, decrypted bool, err error) {
+	encrypted := v.yaml
+	if v.url == "" {
+		data, err = v.readLocal()
+		if err != nil {
+			return nil, false, err
+		}
+	} else {
+		encrypted, err = downloadValues(ctx, v.url)
+		if err != nil {
+			return nil, false, exitcode.New(exitcode.KindInvalidValues, "failed to download values '%s': %v", v.name, err)
+		}
+		data, err = values.Parse(encrypted)
+		if err != nil {
+			return nil, false, exitcode.New(exitcode.KindInvalidValues, "failed to parse values '%s': %v", v.name, err)
+		}
+	}
+
+	// in-memory mappings are never encrypted
+	if v.data != nil || !sops.IsEncrypted(data) {
+		return data, false, nil
+	}
+	plaintext, err := sops.Decrypt(ctx, v.path, encrypted)
+	if err != nil {
+		return nil, false, exitcode.New(exitcode.KindInvalidValues, "failed to decrypt values '%s': %v", v.name, err)
+	}
+	data, err = values.Parse(plaintext)
+	if err != nil {
+		return nil, false, exitcode.New(exitcode.KindInvalidValues, "failed to parse decrypted values '%s': %v", v.name, err)
+	}
+	return data, true, nil
+}
+
+// readLocal returns the Values as a mapping, if they are a file or in memory
+func (v Values) readLocal() (map[string]interface{}, error) {
+	switch {
+	case v.path != "":
+		data, err := values.ReadFile(v.path)
+		if err != nil {
+			return nil, exitcode.Wrap(exitcode.KindInvalidValues, err)
+		}
+		return data, nil
+	case v.data != nil:
+		return v.data, nil
+	default:
+		data, err := values.Parse(v.yaml)
+		if err != nil {
+			return nil, exitcode.New(exitcode.KindInvalidValues, "failed to parse values '%s': %v", v.name, err)
+		}
+		return data, nil
+	}
+}
+
+// downloadValues returns the body of an 'https://' URL
+func downloadValues(ctx context.Context, rawURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status '%s'", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// allValues returns the ValuesFiles and Values of the Options, in order of increasing precedence
+func (o *Options) allValues() []Values {
+	all := make([]Values, 0, len(o.ValuesFiles)+len(o.Values))
+	for _, valuesPath := range o.ValuesFiles {
+		all = append(all, ValuesFromFile(valuesPath))
+	}
+	return append(all, o.Values...)
+}
+
+// checkValues verifies that every values file exists and can be read, and that all the values (except URLs, which
+// are only downloaded when generating) are valid YAML with a mapping at the root and no duplicate keys
+// (for SOPS-encrypted values, this checks the encrypted document)
+//   - note, this happens before the generator source is acquired, so mistakes are found before any downloads
+func checkValues(allValues []Values) error {
+	for _, v := range allValues {
+		if v.path != "" {
+			valuesFileExists, err := generate.FileExists(v.path)
+			if err != nil {
+				return exitcode.Wrap(exitcode.KindInvalidValues, err)
+			}
+			if !valuesFileExists {
+				return exitcode.New(exitcode.KindInvalidValues, "the provided --values file '%s' does not exist", v.path)
+			}
+		}
+		if v.url == "" {
+			data, err := v.readLocal()
+			if err != nil {
+				return err
+			}
+
+			// encrypted values are only decrypted when generating, but the `sops` command must exist
+			if v.data == nil && sops.IsEncrypted(data) {
+				_, err := sops.LookCommand()
+				if err != nil {
+					return exitcode.New(exitcode.KindInvalidValues, "the values '%s' are encrypted with SOPS, but %v", v.name, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// namedValues are the mapping of some Values, see `readAllValues`
+type namedValues struct {
+	name string
+	data map[string]interface{}
+}
+
+// readAllValues reads all the user-provided values, in order
+//   - note, SOPS-encrypted values are decrypted in memory, so the plaintext is never written to disk
+func (r *run) readAllValues(ctx context.Context, allValues []Values) ([]namedValues, error) {
+	all := make([]namedValues, 0, len(allValues))
+	for _, v := range allValues {
+		data, decrypted, err := v.read(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if decrypted {
+			r.log.Infof("Decrypted SOPS-encrypted values: %s", v.name)
+		}
+		all = append(all, namedValues{name: v.name, data: data})
+	}
+	return all, nil
+}
+
+// mergeValues deeply merges all the user-provided values, and then the "key.path" overrides over them
+//   - note, this is the same right-to-left precedence that gomplate's `merge:` datasource uses,
+//     so later values take precedence, nested mappings are merged, and all other values are replaced
+//   - note, the returned mapping never shares nested mappings with the inputs, so they are never modified
+func mergeValues(allValues []namedValues, setValues map[string]interface{}) map[string]interface{} {
+	merged := map[string]interface{}{}
+	for _, v := range allValues {
+		merged = values.Merge(merged, v.data)
+	}
+	if len(setValues) > 0 {
+		merged = values.Merge(merged, nestSetValues(setValues))
+	}
+	return merged
+}
+
+// checkUnknownKeys warns about (or if StrictValues is set, fails on) keys of the user-provided values which
+// don't exist in the `default_values.yaml` (or the `values_schema.json`, if any), as they would have no effect
+func (r *run) checkUnknownKeys(allValues []namedValues, setValues map[string]interface{}, defaultValuesPath string, valuesSchemaPath string) error {
+	if len(allValues) == 0 && len(setValues) == 0 {
+		return nil
+	}
+	defaultValues, err := values.ReadFile(defaultValuesPath)
+	if err != nil {
+		return exitcode.Wrap(exitcode.KindUnsupportedSource, err)
+	}
+
+	// keys which are defined by the values schema are known, even if they are not in the `default_values.yaml`,
+	// and keys which it marks as free-form mappings (like labels) may contain any keys
+	var lookup values.KeyLookup
+	valuesSchemaExists, err := generate.FileExists(valuesSchemaPath)
+	if err != nil {
+		return err
+	}
+	if valuesSchemaExists {
+		valuesSchema, err := schema.Load(valuesSchemaPath)
+		if err != nil {
+			return exitcode.Wrap(exitcode.KindUnsupportedSource, err)
+		}
+		lookup = func(keyPath string) (bool, bool) {
+			property, ok := valuesSchema.Property(keyPath)
+			return ok, property.Open
+		}
+	}
+
+	if len(setValues) > 0 {
+		allValues = append(allValues[:len(allValues):len(allValues)], namedValues{name: "--set", data: nestSetValues(setValues)})
+	}
+	unknownCount := 0
+	for _, v := range allValues {
+		for _, unknownKey := range values.UnknownKeys(v.data, defaultValues, lookup) {
+			unknownCount++
+			message := fmt.Sprintf("unknown key '%s' in values '%s'", unknownKey.KeyPath, v.name)
+			if unknownKey.Suggestion != "" {
+				message += fmt.Sprintf(", did you mean '%s'?", unknownKey.Suggestion)
+			}
+			if r.opts.StrictValues {
+				r.fail(CodeUnknownValuesKey, "%s", message)
+			} else {
+				r.warn(CodeUnknownValuesKey, "%s", message)
+			}
+		}
+	}
+	if unknownCount > 0 && r.opts.StrictValues {
+		return exitcode.New(exitcode.KindInvalidValues, "found %d unknown keys in the values (remove --strict to ignore)", unknownCount)
+	}
+	return nil
+}
+
+// nestSetValues converts "key.path" overrides into nested values
+func nestSetValues(setValues map[string]interface{}) map[string]interface{} {
+	nested := map[string]interface{}{}
+	keys := make([]string, 0, len(setValues))
+	for key := range setValues {
+		keys = append(keys, key)
+	}
+	// NOTE: shorter keys are set first, so a nested key (like "a.b") is not replaced by its parent (like "a")
+	sort.Strings(keys)
+	for _, key := range keys {
+		values.SetPath(nested, key, setValues[key])
+	}
+	return nested
+}
+
+// marshalValues encodes merged values as YAML, so gomplate can read them from memory
+func marshalValues(merged map[string]interface{}) ([]byte, error) {
+	data, err := yaml.Marshal(merged)
+	if err != nil {
+		return nil, exitcode.New(exitcode.KindInvalidValues, "failed to encode the values as YAML: %v", err)
+	}
+	return data, nil
+}